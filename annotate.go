@@ -0,0 +1,167 @@
+package gamebot
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gocv.io/x/gocv"
+)
+
+// jpegQuality is the quality images are saved at by SaveImage when the path has a jpeg extension.
+const jpegQuality = 90
+
+// annotationColor is the color annotations are drawn in when no color is given.
+var annotationColor = color.RGBA{255, 0, 0, 255}
+
+// Annotation is something that can be drawn onto a frame by `(b *Bot) Annotate`.
+// Match, Box and Marker are annotations.
+type Annotation interface {
+	draw(dst *gocv.Mat)
+}
+
+// Box is a labelled rectangle, e.g. a region of the window the bot reads from.
+type Box struct {
+	Label string
+	Rect  image.Rectangle
+	Color color.RGBA
+}
+
+func (a Box) draw(dst *gocv.Mat) {
+	c := colorOrDefault(a.Color)
+
+	gocv.Rectangle(dst, a.Rect, c, 2)
+	drawLabel(dst, a.Label, a.Rect.Min, c)
+}
+
+// Marker is a labelled cross at a single point, e.g. where the bot is about to click.
+type Marker struct {
+	Label string
+	Point image.Point
+	Color color.RGBA
+}
+
+func (a Marker) draw(dst *gocv.Mat) {
+	c := colorOrDefault(a.Color)
+
+	const size = 6
+	gocv.Line(dst, a.Point.Add(image.Pt(-size, 0)), a.Point.Add(image.Pt(size, 0)), c, 2)
+	gocv.Line(dst, a.Point.Add(image.Pt(0, -size)), a.Point.Add(image.Pt(0, size)), c, 2)
+	drawLabel(dst, a.Label, a.Point.Add(image.Pt(size, -size)), c)
+}
+
+// draw draws the area of the best match labelled with the template's name and score,
+// along with a marker at the center of the match.
+func (m Match) draw(dst *gocv.Mat) {
+	label := fmt.Sprintf("%.3f", m.Score)
+	if m.Name != "" {
+		label = fmt.Sprintf("%s %s", m.Name, label)
+	}
+
+	rect := m.Rect()
+	Box{Label: label, Rect: rect, Color: annotationColor}.draw(dst)
+	Marker{Point: rect.Min.Add(rect.Max).Div(2), Color: annotationColor}.draw(dst)
+}
+
+// drawLabel draws `label` just above `pt`, or just below it if there is no room above.
+func drawLabel(dst *gocv.Mat, label string, pt image.Point, c color.RGBA) {
+	if label == "" {
+		return
+	}
+
+	size := gocv.GetTextSize(label, gocv.FontHersheySimplex, 0.5, 1)
+	org := pt.Add(image.Pt(0, -4))
+	if org.Y-size.Y < 0 {
+		org = pt.Add(image.Pt(0, size.Y+4))
+	}
+
+	gocv.PutText(dst, label, org, gocv.FontHersheySimplex, 0.5, c, 1)
+}
+
+// colorOrDefault returns `c`, or the default annotation color if `c` is the zero value.
+func colorOrDefault(c color.RGBA) color.RGBA {
+	if c == (color.RGBA{}) {
+		return annotationColor
+	}
+	return c
+}
+
+// (b *Bot) Annotate returns a copy of `frame` with each of the `annotations` drawn on top of it.
+// This does not need a display so it can be used to produce images of what the bot detected in CI or over SSH.
+func (b *Bot) Annotate(frame *image.Image, annotations ...Annotation) (*image.Image, error) {
+	mat, err := gocv.ImageToMatRGB(*frame)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert img to gocv.Mat: %v", err)
+	}
+	defer mat.Close()
+
+	// Annotations are drawn relative to the top-left corner of the frame.
+	offset := (*frame).Bounds().Min
+	for _, a := range annotations {
+		if offset != (image.Point{}) {
+			a = offsetAnnotation(a, offset)
+		}
+		a.draw(&mat)
+	}
+
+	img, err := mat.ToImage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert gocv.Mat to img: %v", err)
+	}
+
+	return &img, nil
+}
+
+// offsetAnnotation moves `a` so it is relative to an image whose top-left corner is at `offset`.
+func offsetAnnotation(a Annotation, offset image.Point) Annotation {
+	switch v := a.(type) {
+	case Box:
+		v.Rect = v.Rect.Sub(offset)
+		return v
+	case Marker:
+		v.Point = v.Point.Sub(offset)
+		return v
+	case Match:
+		v.Location = v.Location.Sub(offset)
+		return v
+	}
+	return a
+}
+
+// (b *Bot) Crop returns a copy of the `rect` area of `img`. The top-left corner of the returned image is 0, 0.
+func (b *Bot) Crop(img *image.Image, rect image.Rectangle) *image.Image {
+	crop := cropImage(*img, rect.Intersect((*img).Bounds()))
+	return &crop
+}
+
+// (b *Bot) SaveImage writes `img` to `path`. The image is encoded as a PNG or JPEG depending on the extension of `path`.
+// An error is returned if the extension is not .png, .jpg or .jpeg or the file cannot be written.
+func (b *Bot) SaveImage(path string, img *image.Image) error {
+	var encode func(f *os.File) error
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		encode = func(f *os.File) error { return png.Encode(f, *img) }
+	case ".jpg", ".jpeg":
+		encode = func(f *os.File) error { return jpeg.Encode(f, *img, &jpeg.Options{Quality: jpegQuality}) }
+	default:
+		return fmt.Errorf("unsupported image format %q, use .png, .jpg or .jpeg", filepath.Ext(path))
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := encode(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
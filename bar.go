@@ -0,0 +1,162 @@
+package gamebot
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// BarOrientation represents the direction a status bar fills in.
+type BarOrientation int
+
+const (
+	// LeftToRight is a horizontal bar that is full when it reaches the right edge.
+	LeftToRight BarOrientation = iota
+	// RightToLeft is a horizontal bar that is full when it reaches the left edge.
+	RightToLeft
+	// BottomToTop is a vertical bar that is full when it reaches the top edge.
+	BottomToTop
+	// TopToBottom is a vertical bar that is full when it reaches the bottom edge.
+	TopToBottom
+)
+
+// ColorRange represents an inclusive range of colors. A color is within the range if each of its
+// red, green and blue components is between the components of Min and Max. Alpha is ignored.
+type ColorRange struct {
+	Min color.RGBA
+	Max color.RGBA
+}
+
+// (r ColorRange) Contains returns true if `c` is within the range.
+func (r ColorRange) Contains(c color.Color) bool {
+	cr, cg, cb, _ := c.RGBA()
+	r8, g8, b8 := uint8(cr>>8), uint8(cg>>8), uint8(cb>>8)
+
+	return r8 >= r.Min.R && r8 <= r.Max.R &&
+		g8 >= r.Min.G && g8 <= r.Max.G &&
+		b8 >= r.Min.B && b8 <= r.Max.B
+}
+
+// BarReader reads how full a status bar, such as a health, mana or progress bar, is.
+type BarReader struct {
+	region        image.Rectangle
+	referenceSize image.Point
+	orientation   BarOrientation
+	filled        ColorRange
+	empty         ColorRange
+}
+
+// NewBarReader creates a BarReader for the bar within `region` of the bot's window.
+//
+// The `filled` and `empty` parameters are the ranges of colors the filled and empty parts of the bar are drawn in.
+// Pixels that fall in neither range, e.g. text drawn over the bar, are ignored.
+func NewBarReader(region image.Rectangle, orientation BarOrientation, filled, empty ColorRange) *BarReader {
+	return &BarReader{
+		region:      region,
+		orientation: orientation,
+		filled:      filled,
+		empty:       empty,
+	}
+}
+
+// (r *BarReader) SetReferenceSize sets the width and height of the window the bar's region was measured on.
+// When a frame of a different size is read the region is scaled to match, allowing for windows that have been resized.
+func (r *BarReader) SetReferenceSize(w, h int) {
+	r.referenceSize = image.Pt(w, h)
+}
+
+// (b *Bot) ReadBar captures the bot's window and returns how full the bar is. See `(r *BarReader) Read`.
+func (b *Bot) ReadBar(r *BarReader) (float64, error) {
+	return r.Read(b.CaptureWindow())
+}
+
+// (r *BarReader) Read returns how full the bar is in `frame` as a fraction between 0 and 1.
+//
+// The bar is split into single pixel slices across its length. Each slice is classed as filled or empty by
+// the majority of its pixels, which keeps gradients and overlaid text from affecting the result. The fill
+// level is the point along the bar that best separates the filled slices from the empty ones.
+func (r *BarReader) Read(frame *image.Image) (float64, error) {
+	img := *frame
+	fb := img.Bounds()
+
+	region := r.region
+	if r.referenceSize.X > 0 && r.referenceSize.Y > 0 {
+		region = scaleRects([]image.Rectangle{region}, r.referenceSize, fb.Size())[0]
+	}
+	region = region.Add(fb.Min).Intersect(fb)
+	if region.Empty() {
+		return 0, fmt.Errorf("bar region %v is outside of the frame %v", r.region, fb)
+	}
+
+	slices := r.classifySlices(img, region)
+
+	// score[k] is how many slices agree with the bar being filled up to, but not including, slice k.
+	classified := 0
+	score := 0
+	for _, s := range slices {
+		if s < 0 {
+			score++
+		}
+		if s != 0 {
+			classified++
+		}
+	}
+	if classified == 0 {
+		return 0, fmt.Errorf("no filled or empty bar colors were found in %v", region)
+	}
+
+	best, bestScore := 0, score
+	for k, s := range slices {
+		score += s
+		if score > bestScore {
+			best, bestScore = k+1, score
+		}
+	}
+
+	return float64(best) / float64(len(slices)), nil
+}
+
+// classifySlices returns a value for each slice of `region` ordered from the start of the bar to the end.
+// A slice is 1 if it is mostly filled, -1 if it is mostly empty and 0 if it could not be classified.
+func (r *BarReader) classifySlices(img image.Image, region image.Rectangle) []int {
+	horizontal := r.orientation == LeftToRight || r.orientation == RightToLeft
+
+	length, width := region.Dx(), region.Dy()
+	if !horizontal {
+		length, width = region.Dy(), region.Dx()
+	}
+
+	slices := make([]int, length)
+	for i := 0; i < length; i++ {
+		filled, empty := 0, 0
+		for j := 0; j < width; j++ {
+			var x, y int
+			switch r.orientation {
+			case LeftToRight:
+				x, y = region.Min.X+i, region.Min.Y+j
+			case RightToLeft:
+				x, y = region.Max.X-1-i, region.Min.Y+j
+			case BottomToTop:
+				x, y = region.Min.X+j, region.Max.Y-1-i
+			case TopToBottom:
+				x, y = region.Min.X+j, region.Min.Y+i
+			}
+
+			c := img.At(x, y)
+			if r.filled.Contains(c) {
+				filled++
+			} else if r.empty.Contains(c) {
+				empty++
+			}
+		}
+
+		switch {
+		case filled > empty:
+			slices[i] = 1
+		case empty > filled:
+			slices[i] = -1
+		}
+	}
+
+	return slices
+}
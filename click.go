@@ -0,0 +1,172 @@
+package gamebot
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"time"
+)
+
+const (
+	// clickPressMin and clickPressMax represent the default range of time a mouse button is held down when clicking.
+	clickPressMin = 50 * time.Millisecond
+	clickPressMax = 150 * time.Millisecond
+
+	// doubleClickGapMin and doubleClickGapMax represent the range of time between the two clicks of a double click.
+	doubleClickGapMin = 60 * time.Millisecond
+	doubleClickGapMax = 140 * time.Millisecond
+)
+
+// MatchNotFoundError is returned when clicking a Match whose template was not found.
+type MatchNotFoundError struct {
+	Name  string
+	Score float32
+}
+
+func (e *MatchNotFoundError) Error() string {
+	return fmt.Sprintf("MatchNotFoundError: template %s was not found, best score %f", e.Name, e.Score)
+}
+
+func (e *MatchNotFoundError) Is(tgt error) bool {
+	_, ok := tgt.(*MatchNotFoundError)
+	return ok
+}
+
+// NewMatchNotFoundError is returned when clicking a Match whose template was not found.
+func NewMatchNotFoundError(name string, score float32) *MatchNotFoundError {
+	return &MatchNotFoundError{
+		Name:  name,
+		Score: score,
+	}
+}
+
+// clickOptions holds the settings of a single click made by ClickRect or ClickMatch.
+type clickOptions struct {
+	margin      int
+	doubleClick bool
+	pressMin    time.Duration
+	pressMax    time.Duration
+}
+
+// ClickOption changes how ClickRect and ClickMatch click.
+type ClickOption func(o *clickOptions)
+
+// WithMargin keeps the click at least `px` pixels away from the edges of the target. If the target is too small
+// for the margin the center of the target is clicked.
+func WithMargin(px int) ClickOption {
+	return func(o *clickOptions) {
+		o.margin = px
+	}
+}
+
+// WithDoubleClick clicks the target twice.
+func WithDoubleClick() ClickOption {
+	return func(o *clickOptions) {
+		o.doubleClick = true
+	}
+}
+
+// WithPressDuration sets the range of time the mouse button is held down. The duration is drawn at random,
+// favouring the middle of the range. Defaults to 50ms to 150ms.
+func WithPressDuration(min, max time.Duration) ClickOption {
+	return func(o *clickOptions) {
+		o.pressMin = min
+		o.pressMax = max
+	}
+}
+
+func newClickOptions(opts []ClickOption) clickOptions {
+	o := clickOptions{
+		pressMin: clickPressMin,
+		pressMax: clickPressMax,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.pressMax < o.pressMin {
+		o.pressMin, o.pressMax = o.pressMax, o.pressMin
+	}
+
+	return o
+}
+
+// (b *Bot) ClickPoint returns the point ClickRect would click within `rect`, without moving the cursor.
+// Points are drawn from a normal distribution centred on `rect` so clicks land near the middle of the target
+// more often than near its edges, and never outside it.
+func (b *Bot) ClickPoint(rect image.Rectangle, opts ...ClickOption) image.Point {
+	o := newClickOptions(opts)
+
+	rect = rect.Canon().Inset(o.margin)
+	if rect.Empty() {
+		return rect.Min.Add(rect.Max).Div(2)
+	}
+
+	r := b.rand()
+	coord := func(min, max int) int {
+		// Three standard deviations either side of the middle reaches the edges of the target.
+		mid := float64(min+max-1) / 2
+		sd := float64(max-min) / 6
+		return clampInt(int(math.Round(mid+r.NormFloat64()*sd)), min, max-1)
+	}
+
+	return image.Pt(coord(rect.Min.X, rect.Max.X), coord(rect.Min.Y, rect.Max.Y))
+}
+
+// (b *Bot) ClickRect moves the cursor to a random point within `rect`, see `(b *Bot) ClickPoint`, using the bot's
+// motion model then clicks the specified mouse button, holding it down for a human-like length of time.
+// `rect` is in screen coordinates, like the x, y coordinates taken by the other mouse functions.
+// An error is returned, and nothing is clicked, if the cursor cannot be moved, e.g. because the bot is paused, or the
+// bot's input driver fails to press or release the button.
+// The click waits for any queued action that is running, see `(b *Bot) Submit`.
+func (b *Bot) ClickRect(rect image.Rectangle, btn MouseButton, opts ...ClickOption) error {
+	b.config.executor.running.Lock()
+	defer b.config.executor.running.Unlock()
+
+	return b.moveClick(context.Background(), b.ClickPoint(rect, opts...), btn, newClickOptions(opts))
+}
+
+// (b *Bot) ClickMatch clicks a random point within the area of `m`, see `(b *Bot) ClickRect`. The match's location is
+// converted from window coordinates to screen coordinates before clicking. A MatchNotFoundError is returned, and
+// nothing is clicked, if the match's template was not found.
+func (b *Bot) ClickMatch(m Match, btn MouseButton, opts ...ClickOption) error {
+	if !m.Found {
+		return NewMatchNotFoundError(m.Name, m.Score)
+	}
+
+	rect := m.Rect()
+	return b.ClickRect(image.Rectangle{Min: b.WindowToScreen(rect.Min), Max: b.WindowToScreen(rect.Max)}, btn, opts...)
+}
+
+// moveClick moves the cursor to `p` using the bot's motion model then clicks `btn` as set by `o`.
+func (b *Bot) moveClick(ctx context.Context, p image.Point, btn MouseButton, o clickOptions) error {
+	mx, my := b.MousePosition()
+	if err := b.followPath(ctx, b.CursorPath(image.Pt(mx, my), p)); err != nil {
+		return err
+	}
+
+	if err := b.pressRelease(ctx, btn, o); err != nil {
+		return err
+	}
+	if o.doubleClick {
+		time.Sleep(b.randomDuration(doubleClickGapMin, doubleClickGapMax))
+		return b.pressRelease(ctx, btn, o)
+	}
+
+	return nil
+}
+
+// pressRelease presses and releases `btn`, holding it down for a random duration within the range set by `o`.
+func (b *Bot) pressRelease(ctx context.Context, btn MouseButton, o clickOptions) error {
+	if err := b.mousePress(ctx, btn); err != nil {
+		return err
+	}
+	time.Sleep(b.randomDuration(o.pressMin, o.pressMax))
+
+	return b.MouseRelease(btn)
+}
+
+// randomDuration returns a random duration between `min` and `max` that favours the middle of the range.
+func (b *Bot) randomDuration(min, max time.Duration) time.Duration {
+	return DurationRange{Min: min, Max: max}.draw(b.rand())
+}
@@ -0,0 +1,108 @@
+package gamebot
+
+import (
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// exit ends the process once a bot has been closed by CloseOnSignal.
+var exit = os.Exit
+
+// (b *Bot) ReleaseAll releases every key and mouse button the bot has in a down state, including the keys held by
+// `(b *Bot) HoldKey`, no matter how many times they were pressed. If the bot's input driver has a gamepad its buttons
+// are released, its sticks centered and its triggers released too. The first error returned by the bot's input driver
+// is returned once every key has been released.
+func (b *Bot) ReleaseAll() error {
+	b.config.botRWMut.RLock()
+	holds := make([]*KeyHold, 0, len(b.config.holds))
+	for h := range b.config.holds {
+		holds = append(holds, h)
+	}
+	b.config.botRWMut.RUnlock()
+
+	for _, h := range holds {
+		h.Release()
+	}
+
+	b.config.botRWMut.Lock()
+	defer b.config.botRWMut.Unlock()
+
+	var firstErr error
+	for key := range b.config.keysDown {
+		var err error
+		if btn, ok := gamepadButton(key); ok {
+			if pad, padErr := b.gamepad(); padErr == nil {
+				err = pad.ButtonUp(btn)
+			}
+		} else if strings.HasPrefix(key, "mouse") {
+			err = b.config.input.MouseUp(MouseButton(strings.TrimPrefix(key, "mouse")))
+		} else {
+			err = b.config.input.KeyUp(key)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+
+		delete(b.config.keysDown, key)
+	}
+
+	if pad, err := b.gamepad(); err == nil {
+		if err := resetGamepad(pad); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// (b *Bot) Close stops the bot's background goroutines, such as the timers of `(b *Bot) HoldKey`, the handler
+// started by `(b *Bot) CloseOnSignal` and the executor of `(b *Bot) Submit`, and releases every key and mouse button
+// the bot has in a down state.
+// Close should be called once the bot is no longer needed, usually with defer. Closing a bot more than once
+// only releases the keys again.
+func (b *Bot) Close() error {
+	b.config.closeOnce.Do(func() {
+		close(b.config.done)
+	})
+
+	return b.ReleaseAll()
+}
+
+// (b *Bot) CloseOnSignal closes the bot, see `(b *Bot) Close`, when the process receives one of `signals` then exits
+// the process with status 1, so an interrupted bot never leaves keys pressed. If no signals are given the bot is closed
+// on os.Interrupt and SIGTERM. The handler stops once the bot is closed.
+func (b *Bot) CloseOnSignal(signals ...os.Signal) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+
+	go b.closeOnSignal(ch)
+}
+
+// closeOnSignal closes the bot and exits when a signal is received on `ch`, or returns once the bot is closed.
+func (b *Bot) closeOnSignal(ch chan os.Signal) {
+	defer signal.Stop(ch)
+
+	select {
+	case <-ch:
+		b.Close()
+		exit(1)
+	case <-b.config.done:
+	}
+}
+
+// (b *Bot) CloseOnPanic closes the bot, see `(b *Bot) Close`, if the goroutine it is deferred in panics, then carries
+// on panicking. It must be called with defer at the top of main and of every goroutine that sends input:
+//
+//	defer b.CloseOnPanic()
+func (b *Bot) CloseOnPanic() {
+	if r := recover(); r != nil {
+		b.Close()
+		panic(r)
+	}
+}
@@ -1,21 +1,25 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/KalebHawkins/gamebot"
 )
 
 var procName *string
-var imgPath *string
+var imgPaths *string
+var threshold *float64
 
 func parseFlags() {
 	procName = flag.String("process", "", "the process name to target")
-	imgPath = flag.String("image", "", "the image path to detect within the process window")
+	imgPaths = flag.String("image", "", "a comma separated list of image paths to detect within the process window")
+	threshold = flag.Float64("threshold", 0.8, "the score a match must pass to be drawn in green")
 	flag.Parse()
 }
 
@@ -25,26 +29,32 @@ func main() {
 	// Create a new gamebot
 	b, err := gamebot.NewBot(*procName)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to create bot: %s", err)
+		fmt.Fprintf(os.Stderr, "failed to create bot: %s\n", err)
+		os.Exit(1)
 	}
 
-	// Load an image to detect.
-	targetImage, err := b.OpenImage(*imgPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to load image: %s", err)
-	}
-
-	go func() {
-		// This function will open a window and continually update it
-		// drawing a rectangle around the detected image. Press the 'q' key to quit the window.
-		err = b.ShowDetectedImage("Debug Window", targetImage)
+	// Load the images to detect.
+	var tmpls []*gamebot.Template
+	for _, path := range strings.Split(*imgPaths, ",") {
+		tmpl, err := gamebot.LoadTemplate(path)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed during image detection: %s", err)
+			fmt.Fprintf(os.Stderr, "failed to load image: %s\n", err)
+			os.Exit(1)
 		}
-	}()
+		defer tmpl.Close()
 
-	// Block until CTRL+C is pressed.
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
-	<-sigs
+		tmpl.Threshold = float32(*threshold)
+		tmpls = append(tmpls, tmpl)
+	}
+
+	// Stop when CTRL+C is pressed.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	// This function will open a window and continually update it drawing a rectangle around
+	// each detected image. Press the 'q' key to quit the window.
+	if err := b.ShowDetectedImage(ctx, "Debug Window", tmpls...); err != nil {
+		fmt.Fprintf(os.Stderr, "failed during image detection: %s\n", err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,235 @@
+package gamebot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// debugStreamBoundary separates the frames of the MJPEG stream.
+	debugStreamBoundary = "gamebotframe"
+
+	// debugShutdownTimeout represents how long the debug server waits for open streams to close when it is stopped.
+	debugShutdownTimeout = 5 * time.Second
+)
+
+// cursorColor is the color the cursor is drawn in on frames published to a DebugServer.
+var cursorColor = color.RGBA{255, 255, 0, 255}
+
+const debugIndexHTML = `<!DOCTYPE html>
+<html>
+<head><title>gamebot</title></head>
+<body style="margin:0;background:#111;color:#eee;font-family:monospace">
+<img src="/stream" style="max-width:100%">
+<pre id="scores"></pre>
+<script>
+setInterval(function () {
+	fetch("/scores").then(function (r) { return r.json(); }).then(function (s) {
+		document.getElementById("scores").textContent = JSON.stringify(s, null, 2);
+	});
+}, 1000);
+</script>
+</body>
+</html>
+`
+
+// DebugScore is the latest match of a template published to a DebugServer.
+type DebugScore struct {
+	Name     string      `json:"name"`
+	Score    float32     `json:"score"`
+	Location image.Point `json:"location"`
+	Updated  time.Time   `json:"updated"`
+}
+
+// DebugServer is a local HTTP server that lets you watch what a bot sees from a browser. It is useful for bots
+// running over SSH or in a container where `(b *Bot) ShowDetectedImage` cannot open a window.
+//
+// The server has four endpoints:
+//
+//	/           a page showing the stream and the latest scores
+//	/stream     an MJPEG stream of the published frames
+//	/scores     a JSON list of the latest score of every template
+//	/ratelimit  the bot's RateLimitStats as JSON
+type DebugServer struct {
+	mut sync.RWMutex
+
+	bot    *Bot
+	addr   string
+	frame  []byte
+	scores map[string]DebugScore
+
+	// published is closed and replaced each time a frame is published to wake up the open streams.
+	published chan struct{}
+}
+
+// (b *Bot) NewDebugServer creates a DebugServer that will listen on `addr`, e.g. "localhost:8080".
+// The server does not start until `(s *DebugServer) ListenAndServe` is called.
+func (b *Bot) NewDebugServer(addr string) *DebugServer {
+	return &DebugServer{
+		bot:       b,
+		addr:      addr,
+		scores:    make(map[string]DebugScore),
+		published: make(chan struct{}),
+	}
+}
+
+// (s *DebugServer) Publish sends `frame` to every open stream with the `annotations` and the cursor drawn on top of it.
+// The score of every Match in `annotations` is recorded and served by the /scores endpoint.
+func (s *DebugServer) Publish(frame *image.Image, annotations ...Annotation) error {
+	if cursor, ok := s.cursor(frame); ok {
+		annotations = append(annotations, cursor)
+	}
+
+	annotated, err := s.bot.Annotate(frame, annotations...)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, *annotated, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return fmt.Errorf("failed to encode frame: %v", err)
+	}
+
+	now := time.Now()
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	for _, a := range annotations {
+		if m, ok := a.(Match); ok && m.Name != "" {
+			s.scores[m.Name] = DebugScore{Name: m.Name, Score: m.Score, Location: m.Location, Updated: now}
+		}
+	}
+
+	s.frame = buf.Bytes()
+	close(s.published)
+	s.published = make(chan struct{})
+
+	return nil
+}
+
+// cursor returns a marker at the position of the cursor within `frame`, or false if the cursor is outside the frame.
+func (s *DebugServer) cursor(frame *image.Image) (Marker, bool) {
+	mx, my := s.bot.MousePosition()
+
+	pt := s.bot.ScreenToWindow(image.Pt(mx, my)).Add((*frame).Bounds().Min)
+	if !pt.In((*frame).Bounds()) {
+		return Marker{}, false
+	}
+
+	return Marker{Label: "cursor", Point: pt, Color: cursorColor}, true
+}
+
+// (s *DebugServer) Scores returns the latest score of every template published to the server sorted by name.
+func (s *DebugServer) Scores() []DebugScore {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	scores := make([]DebugScore, 0, len(s.scores))
+	for _, score := range s.scores {
+		scores = append(scores, score)
+	}
+	sort.Slice(scores, func(i, j int) bool { return scores[i].Name < scores[j].Name })
+
+	return scores
+}
+
+// (s *DebugServer) Handler returns the server's endpoints as an http.Handler so they can be mounted on another server.
+func (s *DebugServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/stream", s.handleStream)
+	mux.HandleFunc("/scores", s.handleScores)
+	mux.HandleFunc("/ratelimit", s.handleRateLimit)
+
+	return mux
+}
+
+// (s *DebugServer) ListenAndServe starts the server and blocks until `ctx` is cancelled or the server fails.
+// When `ctx` is cancelled the server is shut down and nil is returned.
+func (s *DebugServer) ListenAndServe(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:    s.addr,
+		Handler: s.Handler(),
+		BaseContext: func(_ net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), debugShutdownTimeout)
+		defer cancel()
+
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+func (s *DebugServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, debugIndexHTML)
+}
+
+func (s *DebugServer) handleScores(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.Scores())
+}
+
+func (s *DebugServer) handleRateLimit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.bot.RateLimitStats())
+}
+
+func (s *DebugServer) handleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+debugStreamBoundary)
+	w.Header().Set("Cache-Control", "no-cache")
+
+	for {
+		s.mut.RLock()
+		frame, published := s.frame, s.published
+		s.mut.RUnlock()
+
+		if frame != nil {
+			fmt.Fprintf(w, "--%s\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n", debugStreamBoundary, len(frame))
+			if _, err := w.Write(frame); err != nil {
+				return
+			}
+			fmt.Fprint(w, "\r\n")
+			flusher.Flush()
+		}
+
+		select {
+		case <-published:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,96 @@
+//go:build linux
+
+package gamebot
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// baseDPI is the DPI of an unscaled X display.
+const baseDPI = 96
+
+// randrOutput matches a connected output of `xrandr --query`, capturing whether it is primary, its size in pixels and its
+// physical size in millimetres, e.g. "eDP-1 connected primary 2560x1600+0+0 (normal left) 286mm x 179mm".
+var randrOutput = regexp.MustCompile(`\sconnected\s(primary\s)?(\d+)x(\d+)\+\d+\+\d+.*\s(\d+)mm x (\d+)mm`)
+
+// detectDisplayScale returns the scale factor of the X display. The Xft.dpi resource set by most desktop environments
+// is used when it is available, otherwise the GDK_SCALE and QT_SCALE_FACTOR environment variables are checked.
+// If none of them are set the scale is worked out from the physical DPI of the primary RandR output, and if that is
+// not known either the display is assumed to be unscaled.
+func detectDisplayScale() float64 {
+	if out, err := exec.Command("xrdb", "-query").Output(); err == nil {
+		if dpi, ok := parseXftDPI(out); ok {
+			return dpi / baseDPI
+		}
+	}
+
+	for _, env := range []string{"GDK_SCALE", "QT_SCALE_FACTOR"} {
+		if f, err := strconv.ParseFloat(os.Getenv(env), 64); err == nil && f > 0 {
+			return f
+		}
+	}
+
+	if out, err := exec.Command("xrandr", "--query").Output(); err == nil {
+		if dpi, ok := parseRandRDPI(out); ok {
+			// Desktops without a configured DPI scale by whole numbers, e.g. a 220 DPI laptop screen by 2.
+			return math.Max(1, math.Floor(dpi/baseDPI))
+		}
+	}
+
+	return 1
+}
+
+// parseXftDPI returns the value of the Xft.dpi resource from the output of `xrdb -query`.
+func parseXftDPI(resources []byte) (float64, bool) {
+	s := bufio.NewScanner(bytes.NewReader(resources))
+	for s.Scan() {
+		name, value, ok := strings.Cut(s.Text(), ":")
+		if !ok || strings.TrimSpace(name) != "Xft.dpi" {
+			continue
+		}
+
+		dpi, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || dpi <= 0 {
+			return 0, false
+		}
+		return dpi, true
+	}
+
+	return 0, false
+}
+
+// parseRandRDPI returns the physical DPI of the primary output, or of the first connected output if none is primary,
+// from the output of `xrandr --query`. Outputs that do not report a physical size, such as virtual displays, are
+// skipped.
+func parseRandRDPI(query []byte) (float64, bool) {
+	var dpi float64
+	s := bufio.NewScanner(bytes.NewReader(query))
+	for s.Scan() {
+		m := randrOutput.FindStringSubmatch(s.Text())
+		if m == nil {
+			continue
+		}
+
+		width, _ := strconv.ParseFloat(m[2], 64)
+		mm, _ := strconv.ParseFloat(m[4], 64)
+		if mm <= 0 {
+			continue
+		}
+
+		if m[1] != "" {
+			return width / (mm / 25.4), true
+		}
+		if dpi == 0 {
+			dpi = width / (mm / 25.4)
+		}
+	}
+
+	return dpi, dpi > 0
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package gamebot
+
+import "github.com/go-vgo/robotgo"
+
+// detectDisplayScale returns the scale factor of the main display as reported by the operating system.
+func detectDisplayScale() float64 {
+	if f := robotgo.SysScale(); f > 0 {
+		return f
+	}
+	return 1
+}
@@ -0,0 +1,336 @@
+package gamebot
+
+import (
+	"container/heap"
+	"context"
+	"image"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Priority orders the actions queued on the bot, see `(b *Bot) Submit`. Actions with a higher priority run first.
+type Priority int
+
+const (
+	PriorityLow    Priority = -10
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 10
+)
+
+// Action is a unit of input run by the bot's executor, e.g. a move followed by a click. No other queued action runs
+// while an action is running, nor does a high-level input method called directly by another goroutine, such as
+// `(b *Bot) ClickRect` or `(b *Bot) Drag`, so its input is never interleaved with the input of another goroutine.
+// An action may call those methods itself, but must not wait on goroutines of its own that call them, as they wait
+// for the action to finish.
+type Action func(ctx context.Context) error
+
+// ActionResult is the result of a queued action, which is known once the action has run.
+type ActionResult struct {
+	done chan struct{}
+	err  error
+}
+
+// (r *ActionResult) Done returns a channel that is closed once the action has run or been cancelled.
+func (r *ActionResult) Done() <-chan struct{} {
+	return r.done
+}
+
+// (r *ActionResult) Err returns the error returned by the action, or nil if the action has not finished or succeeded.
+func (r *ActionResult) Err() error {
+	select {
+	case <-r.done:
+		return r.err
+	default:
+		return nil
+	}
+}
+
+// (r *ActionResult) Wait blocks until the action has run and returns its error, or until `ctx` is cancelled and
+// returns the context's error. Cancelling `ctx` does not cancel the action.
+func (r *ActionResult) Wait(ctx context.Context) error {
+	select {
+	case <-r.done:
+		return r.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// complete records the result of the action and wakes up everything waiting for it.
+func (r *ActionResult) complete(err error) {
+	r.err = err
+	close(r.done)
+}
+
+// queuedAction is an action waiting in the executor's queue.
+type queuedAction struct {
+	ctx      context.Context
+	action   Action
+	priority Priority
+	// seq orders actions of the same priority by when they were submitted.
+	seq    uint64
+	result *ActionResult
+	// index is the position of the action in the queue, or -1 once it has left the queue.
+	index int
+}
+
+// actionQueue is a heap of queued actions, highest priority first.
+type actionQueue []*queuedAction
+
+func (q actionQueue) Len() int { return len(q) }
+
+func (q actionQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q actionQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *actionQueue) Push(x interface{}) {
+	a := x.(*queuedAction)
+	a.index = len(*q)
+	*q = append(*q, a)
+}
+
+func (q *actionQueue) Pop() interface{} {
+	old := *q
+	a := old[len(old)-1]
+	old[len(old)-1] = nil
+	a.index = -1
+	*q = old[:len(old)-1]
+	return a
+}
+
+// executor runs the actions queued on a bot one at a time. Its goroutine is started by the first action submitted.
+type executor struct {
+	mut sync.Mutex
+
+	queue   actionQueue
+	seq     uint64
+	started bool
+	// closed is set once the executor has drained its queue because the bot was closed, after which nothing reads
+	// the queue.
+	closed bool
+	// wake is signalled each time an action is queued.
+	wake chan struct{}
+	// running is held while an action runs, and by the high-level input methods, e.g. `(b *Bot) ClickRect`, while they
+	// are called directly, so their input is not interleaved with that of a queued action.
+	running actionMutex
+}
+
+// newExecutor creates an executor with an empty queue.
+func newExecutor() *executor {
+	e := &executor{
+		wake: make(chan struct{}, 1),
+	}
+	e.running.cond = sync.NewCond(&e.running.mut)
+
+	return e
+}
+
+// actionMutex is a mutex that may be locked again by the goroutine holding it, so an action run by the executor can
+// call the high-level input methods that lock it, as can a method that calls another.
+type actionMutex struct {
+	mut  sync.Mutex
+	cond *sync.Cond
+
+	// owner is the id of the goroutine holding the mutex and depth is how many times it has locked it.
+	owner uint64
+	depth int
+}
+
+// Lock waits until the mutex is free or held by the calling goroutine, then locks it.
+func (m *actionMutex) Lock() {
+	id := goroutineID()
+
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	for m.depth > 0 && m.owner != id {
+		m.cond.Wait()
+	}
+	m.owner = id
+	m.depth++
+}
+
+// Unlock undoes one call to Lock. The mutex is free once it has been unlocked as many times as it was locked.
+func (m *actionMutex) Unlock() {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	m.depth--
+	if m.depth == 0 {
+		m.owner = 0
+		m.cond.Broadcast()
+	}
+}
+
+// goroutineID returns the id of the calling goroutine, read from the first line of its stack trace, e.g.
+// "goroutine 18 [running]:".
+func goroutineID() uint64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	fields := strings.Fields(strings.TrimPrefix(string(buf[:n]), "goroutine "))
+	if len(fields) == 0 {
+		return 0
+	}
+
+	id, _ := strconv.ParseUint(fields[0], 10, 64)
+	return id
+}
+
+// (b *Bot) Submit queues `action` to run once every action queued before it with the same or a higher priority has
+// run, and returns its result without waiting for it. Actions with a higher priority jump ahead of queued actions with
+// a lower priority, e.g. a PriorityHigh action to drink a potion runs before the PriorityLow loot clicks already
+// queued. An action that is running is never interrupted.
+//
+// `ctx` is passed to the action. If `ctx` is cancelled before the action runs the action is removed from the queue
+// and its result is the context's error. Once the bot is closed queued actions are not run and their result is
+// ErrBotKilled.
+func (b *Bot) Submit(ctx context.Context, priority Priority, action Action) *ActionResult {
+	e := b.config.executor
+	result := &ActionResult{done: make(chan struct{})}
+
+	if err := ctx.Err(); err != nil {
+		result.complete(err)
+		return result
+	}
+
+	e.mut.Lock()
+	closed := e.closed
+	select {
+	case <-b.config.done:
+		closed = true
+	default:
+	}
+	if closed {
+		e.mut.Unlock()
+		result.complete(ErrBotKilled)
+		return result
+	}
+	e.seq++
+	a := &queuedAction{ctx: ctx, action: action, priority: priority, seq: e.seq, result: result}
+	heap.Push(&e.queue, a)
+	if !e.started {
+		e.started = true
+		go b.runActions()
+	}
+	e.mut.Unlock()
+
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+
+	if ctx.Done() != nil {
+		go b.cancelOnDone(a)
+	}
+
+	return result
+}
+
+// (b *Bot) Run queues `action`, see `(b *Bot) Submit`, and waits for it to run. The action's error is returned.
+func (b *Bot) Run(ctx context.Context, priority Priority, action Action) error {
+	return b.Submit(ctx, priority, action).Wait(context.Background())
+}
+
+// (b *Bot) QueueMoveClick queues moving the cursor to `p` using the bot's motion model then clicking `btn`, so the
+// click cannot land somewhere else because another goroutine moved the cursor. `p` is in screen coordinates.
+// WithMargin has no effect on a click at a point.
+func (b *Bot) QueueMoveClick(ctx context.Context, priority Priority, p image.Point, btn MouseButton, opts ...ClickOption) *ActionResult {
+	o := newClickOptions(opts)
+	return b.Submit(ctx, priority, func(ctx context.Context) error {
+		return b.moveClick(ctx, p, btn, o)
+	})
+}
+
+// (b *Bot) QueueDrag queues a drag from `from` to `to`, see `(b *Bot) Drag`.
+func (b *Bot) QueueDrag(ctx context.Context, priority Priority, from, to image.Point, btn MouseButton, opts *DragOptions) *ActionResult {
+	return b.Submit(ctx, priority, func(ctx context.Context) error {
+		return b.Drag(ctx, from, to, btn, opts)
+	})
+}
+
+// (b *Bot) QueueTypeText queues typing `s`, see `(b *Bot) TypeText`, so no other queued input lands in the middle of it.
+func (b *Bot) QueueTypeText(ctx context.Context, priority Priority, s string) *ActionResult {
+	return b.Submit(ctx, priority, func(ctx context.Context) error {
+		return b.TypeText(ctx, s)
+	})
+}
+
+// runActions runs the queued actions one at a time until the bot is closed.
+func (b *Bot) runActions() {
+	e := b.config.executor
+
+	for {
+		e.mut.Lock()
+		var a *queuedAction
+		if e.queue.Len() > 0 {
+			a = heap.Pop(&e.queue).(*queuedAction)
+		}
+		e.mut.Unlock()
+
+		if a == nil {
+			select {
+			case <-b.config.done:
+				b.drainActions()
+				return
+			case <-e.wake:
+			}
+			continue
+		}
+
+		select {
+		case <-b.config.done:
+			a.result.complete(ErrBotKilled)
+			b.drainActions()
+			return
+		default:
+		}
+
+		e.running.Lock()
+		err := a.action(a.ctx)
+		e.running.Unlock()
+		a.result.complete(err)
+	}
+}
+
+// drainActions removes every action from the queue with ErrBotKilled as its result and marks the executor as closed, so
+// actions submitted afterwards are not queued.
+func (b *Bot) drainActions() {
+	e := b.config.executor
+
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	e.closed = true
+	for e.queue.Len() > 0 {
+		heap.Pop(&e.queue).(*queuedAction).result.complete(ErrBotKilled)
+	}
+}
+
+// cancelOnDone removes `a` from the queue, with the context's error as its result, if its context is cancelled before
+// it runs.
+func (b *Bot) cancelOnDone(a *queuedAction) {
+	e := b.config.executor
+
+	select {
+	case <-a.result.done:
+	case <-a.ctx.Done():
+		e.mut.Lock()
+		defer e.mut.Unlock()
+
+		if a.index >= 0 {
+			heap.Remove(&e.queue, a.index)
+			a.result.complete(a.ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package gamebot
+
+import (
+	"sort"
+	"sync"
+)
+
+// FakeDriver is an InputDriver that records input instead of sending it to the operating system.
+// It lets bots be tested without moving the real cursor or pressing real keys.
+type FakeDriver struct {
+	mut sync.Mutex
+
+	events []InputEvent
+	down   map[string]bool
+	x, y   int
+}
+
+// NewFakeDriver creates a FakeDriver with the cursor at 0, 0 and no keys down.
+func NewFakeDriver() *FakeDriver {
+	return &FakeDriver{
+		down: make(map[string]bool),
+	}
+}
+
+func (d *FakeDriver) KeyDown(key string) error {
+	d.record(InputEvent{Action: KeyDownAction, Key: key})
+	return nil
+}
+
+func (d *FakeDriver) KeyUp(key string) error {
+	d.record(InputEvent{Action: KeyUpAction, Key: key})
+	return nil
+}
+
+func (d *FakeDriver) MouseDown(btn MouseButton) error {
+	d.record(InputEvent{Action: MouseDownAction, Key: string(btn)})
+	return nil
+}
+
+func (d *FakeDriver) MouseUp(btn MouseButton) error {
+	d.record(InputEvent{Action: MouseUpAction, Key: string(btn)})
+	return nil
+}
+
+func (d *FakeDriver) Move(x, y int) error {
+	d.record(InputEvent{Action: MoveAction, X: x, Y: y})
+	return nil
+}
+
+func (d *FakeDriver) MousePosition() (int, int) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	return d.x, d.y
+}
+
+func (d *FakeDriver) Scroll(x, y int) error {
+	d.record(InputEvent{Action: ScrollAction, X: x, Y: y})
+	return nil
+}
+
+func (d *FakeDriver) Type(s string) error {
+	d.record(InputEvent{Action: TypeAction, Key: s})
+	return nil
+}
+
+// record appends `e` to the driver's events and applies it to the driver's state.
+func (d *FakeDriver) record(e InputEvent) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	d.events = append(d.events, e)
+
+	switch e.Action {
+	case KeyDownAction:
+		d.down[e.Key] = true
+	case KeyUpAction:
+		delete(d.down, e.Key)
+	case MouseDownAction:
+		d.down["mouse"+e.Key] = true
+	case MouseUpAction:
+		delete(d.down, "mouse"+e.Key)
+	case MoveAction:
+		d.x, d.y = e.X, e.Y
+	}
+}
+
+// (d *FakeDriver) Events returns every event the driver has received in the order it received them.
+func (d *FakeDriver) Events() []InputEvent {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	return append([]InputEvent{}, d.events...)
+}
+
+// (d *FakeDriver) Down returns the keys the driver has in a down state sorted by name.
+// Mouse buttons are prefixed with the string mouse, like `(b *Bot) KeysDown`.
+func (d *FakeDriver) Down() []string {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	keys := make([]string, 0, len(d.down))
+	for k := range d.down {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// (d *FakeDriver) Reset forgets the driver's events. The keys down and the position of the cursor are kept.
+func (d *FakeDriver) Reset() {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	d.events = nil
+}
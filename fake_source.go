@@ -0,0 +1,71 @@
+package gamebot
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeEventSource is an EventSource whose events are sent by the caller instead of the operating system.
+// It lets hotkeys and macro recording be tested with synthetic input.
+type FakeEventSource struct {
+	mut sync.Mutex
+
+	listeners map[chan HookEvent]struct{}
+	// pending holds the events sent before anything was listening. They are delivered to the first listener.
+	pending []HookEvent
+}
+
+// NewFakeEventSource creates a FakeEventSource with no listeners.
+func NewFakeEventSource() *FakeEventSource {
+	return &FakeEventSource{
+		listeners: make(map[chan HookEvent]struct{}),
+	}
+}
+
+func (s *FakeEventSource) Listen() (<-chan HookEvent, func(), error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	ch := make(chan HookEvent, hookListenerBuffer+len(s.pending))
+	for _, e := range s.pending {
+		ch <- e
+	}
+	s.pending = nil
+	s.listeners[ch] = struct{}{}
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			s.mut.Lock()
+			defer s.mut.Unlock()
+
+			delete(s.listeners, ch)
+			close(ch)
+		})
+	}
+
+	return ch, stop, nil
+}
+
+// (s *FakeEventSource) Send delivers `e` to every listener as if it had just been made by the player.
+// If nothing is listening the event is kept until something does.
+func (s *FakeEventSource) Send(e InputEvent) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	he := HookEvent{InputEvent: e, When: time.Now()}
+	if len(s.listeners) == 0 {
+		s.pending = append(s.pending, he)
+		return
+	}
+
+	for ch := range s.listeners {
+		ch <- he
+	}
+}
+
+// (s *FakeEventSource) Tap sends a key down event followed by a key up event for `key`.
+func (s *FakeEventSource) Tap(key Key) {
+	s.Send(InputEvent{Action: KeyDownAction, Key: string(key)})
+	s.Send(InputEvent{Action: KeyUpAction, Key: string(key)})
+}
@@ -0,0 +1,272 @@
+package gamebot
+
+import (
+	"image"
+	"math"
+	"math/bits"
+	"sort"
+	"sync"
+)
+
+// ImageHash is a 64 bit perceptual hash of an image. Similar images produce hashes that differ in only a few bits.
+type ImageHash uint64
+
+// (h ImageHash) Distance returns the Hamming distance between two hashes, the number of bits that differ.
+// A distance of 0 means the images are very likely the same, a distance above 10 usually means they are different.
+func (h ImageHash) Distance(o ImageHash) int {
+	return bits.OnesCount64(uint64(h ^ o))
+}
+
+// HashAlgorithm represents the algorithm used to calculate an ImageHash.
+type HashAlgorithm int
+
+const (
+	// AverageHashAlgorithm compares each cell of an 8x8 grid with the average brightness. It is the fastest but
+	// the most sensitive to changes in brightness.
+	AverageHashAlgorithm HashAlgorithm = iota
+	// DifferenceHashAlgorithm compares the brightness of neighbouring cells of a 9x8 grid.
+	DifferenceHashAlgorithm
+	// PerceptualHashAlgorithm compares the low frequencies of a discrete cosine transform. It is the slowest
+	// but the most robust to small changes such as scaling, compression and gamma.
+	PerceptualHashAlgorithm
+)
+
+// (a HashAlgorithm) Hash calculates the hash of `img` using the algorithm.
+func (a HashAlgorithm) Hash(img image.Image) ImageHash {
+	switch a {
+	case DifferenceHashAlgorithm:
+		return DifferenceHash(img)
+	case PerceptualHashAlgorithm:
+		return PerceptualHash(img)
+	default:
+		return AverageHash(img)
+	}
+}
+
+// AverageHash calculates the aHash of `img`.
+func AverageHash(img image.Image) ImageHash {
+	grid := grayGrid(img, 8, 8)
+
+	mean := 0.0
+	for _, v := range grid {
+		mean += v
+	}
+	mean /= float64(len(grid))
+
+	var hash ImageHash
+	for _, v := range grid {
+		hash <<= 1
+		if v > mean {
+			hash |= 1
+		}
+	}
+
+	return hash
+}
+
+// DifferenceHash calculates the dHash of `img`.
+func DifferenceHash(img image.Image) ImageHash {
+	grid := grayGrid(img, 9, 8)
+
+	var hash ImageHash
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if grid[y*9+x] < grid[y*9+x+1] {
+				hash |= 1
+			}
+		}
+	}
+
+	return hash
+}
+
+// PerceptualHash calculates the pHash of `img`.
+func PerceptualHash(img image.Image) ImageHash {
+	const size = 32
+
+	grid := grayGrid(img, size, size)
+	dct := dct2D(grid, size)
+
+	// Keep the 8x8 lowest frequencies and compare each with their median, skipping the DC term
+	// when calculating the median as it only reflects the average brightness.
+	low := make([]float64, 0, 64)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			low = append(low, dct[y*size+x])
+		}
+	}
+
+	sorted := append([]float64{}, low[1:]...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	var hash ImageHash
+	for _, v := range low {
+		hash <<= 1
+		if v > median {
+			hash |= 1
+		}
+	}
+
+	return hash
+}
+
+// dct2D calculates the two dimensional type II discrete cosine transform of an `n` by `n` grid.
+func dct2D(grid []float64, n int) []float64 {
+	cos := make([]float64, n*n)
+	for k := 0; k < n; k++ {
+		for i := 0; i < n; i++ {
+			cos[k*n+i] = math.Cos(math.Pi / float64(n) * (float64(i) + 0.5) * float64(k))
+		}
+	}
+
+	// Transform the rows then the columns.
+	rows := make([]float64, n*n)
+	for y := 0; y < n; y++ {
+		for k := 0; k < n; k++ {
+			sum := 0.0
+			for x := 0; x < n; x++ {
+				sum += grid[y*n+x] * cos[k*n+x]
+			}
+			rows[y*n+k] = sum
+		}
+	}
+
+	out := make([]float64, n*n)
+	for x := 0; x < n; x++ {
+		for k := 0; k < n; k++ {
+			sum := 0.0
+			for y := 0; y < n; y++ {
+				sum += rows[y*n+x] * cos[k*n+y]
+			}
+			out[k*n+x] = sum
+		}
+	}
+
+	return out
+}
+
+// grayGrid shrinks `img` to a `w` by `h` grid of average brightness values.
+func grayGrid(img image.Image, w, h int) []float64 {
+	b := img.Bounds()
+	grid := make([]float64, w*h)
+	counts := make([]int, w*h)
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		gy := (y - b.Min.Y) * h / b.Dy()
+		for x := b.Min.X; x < b.Max.X; x++ {
+			gx := (x - b.Min.X) * w / b.Dx()
+			r, g, bl, _ := img.At(x, y).RGBA()
+			grid[gy*w+gx] += 0.299*float64(r>>8) + 0.587*float64(g>>8) + 0.114*float64(bl>>8)
+			counts[gy*w+gx]++
+		}
+	}
+
+	for i := range grid {
+		if counts[i] > 0 {
+			grid[i] /= float64(counts[i])
+		}
+	}
+
+	return grid
+}
+
+// HashRegion calculates the hash of the `region` area of `frame` using `algorithm`.
+// If `region` is empty the whole frame is hashed.
+func HashRegion(algorithm HashAlgorithm, frame *image.Image, region image.Rectangle) ImageHash {
+	img := *frame
+	if !region.Empty() {
+		img = cropImage(img, region.Add(img.Bounds().Min).Intersect(img.Bounds()))
+	}
+
+	return algorithm.Hash(img)
+}
+
+// FingerprintStore keeps the hashes of named areas of a window so a bot can cheaply tell when part of the screen
+// has changed, e.g. to skip template matching a panel that looks the same as it did on the last tick.
+type FingerprintStore struct {
+	mut sync.RWMutex
+
+	bot       *Bot
+	algorithm HashAlgorithm
+	prints    map[string]fingerprint
+}
+
+type fingerprint struct {
+	region image.Rectangle
+	hash   ImageHash
+}
+
+// (b *Bot) NewFingerprintStore creates an empty FingerprintStore that hashes with `algorithm`.
+func (b *Bot) NewFingerprintStore(algorithm HashAlgorithm) *FingerprintStore {
+	return &FingerprintStore{
+		bot:       b,
+		algorithm: algorithm,
+		prints:    make(map[string]fingerprint),
+	}
+}
+
+// (s *FingerprintStore) Set hashes the `region` area of `frame` and stores it as `name`, replacing any previous hash.
+// If `region` is empty the whole frame is hashed.
+func (s *FingerprintStore) Set(name string, frame *image.Image, region image.Rectangle) ImageHash {
+	hash := HashRegion(s.algorithm, frame, region)
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.prints[name] = fingerprint{region: region, hash: hash}
+	return hash
+}
+
+// (s *FingerprintStore) Get returns the hash stored as `name` and true, or false if there is no such hash.
+func (s *FingerprintStore) Get(name string) (ImageHash, bool) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	fp, ok := s.prints[name]
+	return fp.hash, ok
+}
+
+// (s *FingerprintStore) Delete removes the hash stored as `name`.
+func (s *FingerprintStore) Delete(name string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	delete(s.prints, name)
+}
+
+// (s *FingerprintStore) Matches returns true if the area of `frame` covered by the `name` fingerprint is within
+// `maxDistance` of the stored hash. False is returned if there is no fingerprint called `name`.
+func (s *FingerprintStore) Matches(name string, frame *image.Image, maxDistance int) bool {
+	s.mut.RLock()
+	fp, ok := s.prints[name]
+	s.mut.RUnlock()
+
+	if !ok {
+		return false
+	}
+
+	return HashRegion(s.algorithm, frame, fp.region).Distance(fp.hash) <= maxDistance
+}
+
+// (s *FingerprintStore) Changed hashes the `region` area of `frame` and returns true if it is more than
+// `maxDistance` from the hash stored as `name`, or if there is no hash stored as `name`. The new hash is
+// stored so each call compares the frame with the frame of the previous call.
+func (s *FingerprintStore) Changed(name string, frame *image.Image, region image.Rectangle, maxDistance int) bool {
+	hash := HashRegion(s.algorithm, frame, region)
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	prev, ok := s.prints[name]
+	s.prints[name] = fingerprint{region: region, hash: hash}
+
+	return !ok || prev.region != region || prev.hash.Distance(hash) > maxDistance
+}
+
+// (s *FingerprintStore) WindowChanged captures the bot's window and reports whether the `region` area has changed
+// since the last call. See `(s *FingerprintStore) Changed`.
+func (s *FingerprintStore) WindowChanged(name string, region image.Rectangle, maxDistance int) bool {
+	return s.Changed(name, s.bot.CaptureWindow(), region, maxDistance)
+}
@@ -0,0 +1,187 @@
+package gamebot
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"time"
+)
+
+// ErrWindowNotFocused is returned when the focus guard refuses input because the bot's window does not have focus,
+// see `(b *Bot) SetFocusGuard`.
+var ErrWindowNotFocused = errors.New("bot's window is not focused")
+
+const (
+	// focusActivateTimeout is how long FocusActivate waits for the window to become active when no timeout is set.
+	focusActivateTimeout = 2 * time.Second
+	// focusPollInterval is how often the focus guard checks whether the window has focus while it waits.
+	focusPollInterval = 50 * time.Millisecond
+)
+
+// FocusPolicy is what the focus guard does when the bot's window does not have focus.
+type FocusPolicy int
+
+const (
+	// FocusOff does not check the window's focus. Input goes to whatever window has focus.
+	FocusOff FocusPolicy = iota
+	// FocusActivate activates the bot's window and waits for it to get focus.
+	FocusActivate
+	// FocusWait waits for the window to get focus again, e.g. for the player to close a popup.
+	FocusWait
+	// FocusReject refuses the input with ErrWindowNotFocused.
+	FocusReject
+)
+
+// FocusGuard makes sure the bot only sends input to its own window, so a chat popup or notification that steals focus
+// is never typed into. See `(b *Bot) SetFocusGuard`.
+type FocusGuard struct {
+	// Policy is what the guard does when the window does not have focus.
+	Policy FocusPolicy
+	// Timeout is how long FocusActivate and FocusWait wait for the window to get focus before ErrWindowNotFocused is
+	// returned. If it is 0 FocusActivate waits 2 seconds and FocusWait waits until the window gets focus.
+	Timeout time.Duration
+}
+
+// focusWindow is a window the focus guard can check and activate. *window is a focusWindow.
+type focusWindow interface {
+	IsActive() bool
+	SetActive()
+}
+
+// PointOutsideWindowError is returned when the focus guard refuses a mouse action because its point is outside the
+// bot's window.
+type PointOutsideWindowError struct {
+	Point  image.Point
+	Bounds image.Rectangle
+}
+
+func (e *PointOutsideWindowError) Error() string {
+	return fmt.Sprintf("PointOutsideWindowError: %v is outside of the window %v", e.Point, e.Bounds)
+}
+
+func (e *PointOutsideWindowError) Is(tgt error) bool {
+	_, ok := tgt.(*PointOutsideWindowError)
+	return ok
+}
+
+// NewPointOutsideWindowError is returned when the focus guard refuses a mouse action because its point is outside the
+// bot's window.
+func NewPointOutsideWindowError(p image.Point, bounds image.Rectangle) *PointOutsideWindowError {
+	return &PointOutsideWindowError{
+		Point:  p,
+		Bounds: bounds,
+	}
+}
+
+// (b *Bot) SetFocusGuard sets what the bot does when its window does not have focus. Unless the policy is FocusOff the
+// window's focus is checked before every key press, mouse button press, scroll and cursor movement, and mouse input
+// whose point is outside the window is refused with a PointOutsideWindowError. Releases are never guarded, so a
+// guarded bot never leaves keys pressed.
+func (b *Bot) SetFocusGuard(g FocusGuard) {
+	b.config.botRWMut.Lock()
+	defer b.config.botRWMut.Unlock()
+
+	b.config.focusGuard = g
+}
+
+// (b *Bot) FocusGuard returns what the bot does when its window does not have focus.
+func (b *Bot) FocusGuard() FocusGuard {
+	b.config.botRWMut.RLock()
+	defer b.config.botRWMut.RUnlock()
+
+	return b.config.focusGuard
+}
+
+// focusTarget returns the window the focus guard keeps input in.
+func (b *Bot) focusTarget() focusWindow {
+	b.config.botRWMut.RLock()
+	defer b.config.botRWMut.RUnlock()
+
+	if b.config.focusWindow != nil {
+		return b.config.focusWindow
+	}
+	return b.config.window
+}
+
+// guardFocus applies the focus guard's policy if the bot's window does not have focus. It returns nil once the window
+// has focus, ErrWindowNotFocused if it does not get focus in time and ErrBotPaused or ErrBotKilled if the bot is paused
+// or closed while waiting.
+func (b *Bot) guardFocus() error {
+	g := b.FocusGuard()
+	if g.Policy == FocusOff {
+		return nil
+	}
+
+	w := b.focusTarget()
+	if w.IsActive() {
+		return nil
+	}
+
+	switch g.Policy {
+	case FocusReject:
+		return ErrWindowNotFocused
+	case FocusActivate:
+		w.SetActive()
+		if g.Timeout <= 0 {
+			g.Timeout = focusActivateTimeout
+		}
+	}
+
+	var timeout <-chan time.Time
+	if g.Timeout > 0 {
+		t := time.NewTimer(g.Timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	poll := time.NewTicker(focusPollInterval)
+	defer poll.Stop()
+
+	for {
+		b.config.botRWMut.RLock()
+		err := b.inputErr()
+		changed := b.config.pauseChanged
+		b.config.botRWMut.RUnlock()
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-b.config.done:
+			return ErrBotKilled
+		case <-changed:
+		case <-timeout:
+			return ErrWindowNotFocused
+		case <-poll.C:
+			if w.IsActive() {
+				return nil
+			}
+		}
+	}
+}
+
+// guardPoint applies the focus guard to mouse input at `p`, a point on the screen. A PointOutsideWindowError is
+// returned if the guard is on and `p` is outside the bot's window.
+func (b *Bot) guardPoint(p image.Point) error {
+	if b.FocusGuard().Policy == FocusOff {
+		return nil
+	}
+	if err := b.guardFocus(); err != nil {
+		return err
+	}
+
+	if bounds := b.WindowBounds(); !p.In(bounds) {
+		return NewPointOutsideWindowError(p, bounds)
+	}
+	return nil
+}
+
+// guardCursor applies the focus guard to mouse input at the current location of the cursor.
+func (b *Bot) guardCursor() error {
+	if b.FocusGuard().Policy == FocusOff {
+		return nil
+	}
+
+	x, y := b.MousePosition()
+	return b.guardPoint(image.Pt(x, y))
+}
@@ -2,6 +2,7 @@ package gamebot
 
 import (
 	"math/rand"
+	"runtime"
 	"sync"
 
 	"github.com/go-vgo/robotgo"
@@ -28,10 +29,58 @@ type botConfig struct {
 
 	screenCaptureDelayMs int
 
-	// keysDown keeps track of all the keys in a down state.
-	keysDown map[string]bool
+	// keysDown keeps track of all the keys in a down state and how many times each has been pressed.
+	keysDown map[string]int
 
 	cvMatchMode gocv.TemplateMatchMode
+
+	// detectWorkers is the maximum number of templates DetectMany will match concurrently.
+	detectWorkers int
+
+	// displayScale is the number of captured pixels per logical screen unit.
+	displayScale float64
+
+	// random is the source of every random decision the bot makes, such as the path the cursor takes.
+	random *rand.Rand
+
+	// motionModel generates the paths the cursor follows.
+	motionModel MotionModel
+
+	// keyTiming controls how long keys are held and the time between them.
+	keyTiming KeyTiming
+
+	// input sends keyboard and mouse input to the operating system.
+	input InputDriver
+
+	// holds are the KeyHolds that have not been released yet.
+	holds map[*KeyHold]struct{}
+
+	// done is closed when the bot is closed to stop the bot's background goroutines.
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// events delivers the input made anywhere on the system for hotkeys and macros.
+	events  EventSource
+	hotkeys []hotkey
+
+	// paused, stepping and killed control whether the bot may send input. stepRequested lets a paused bot
+	// run until it next waits. pauseChanged is closed and replaced each time they change.
+	paused        bool
+	stepping      bool
+	stepRequested bool
+	killed        bool
+	pauseChanged  chan struct{}
+
+	// limiter caps how quickly the bot sends input, see SetRateLimit.
+	limiter *rateLimiter
+
+	// focusGuard is what the bot does when its window does not have focus. focusWindow replaces the bot's window
+	// as the window the guard checks, e.g. in tests.
+	focusGuard  FocusGuard
+	focusWindow focusWindow
+
+	// executor runs the actions queued with Submit one at a time.
+	executor *executor
 }
 
 // NewBot create a new bot instance.
@@ -46,11 +95,24 @@ func NewBot(processName string) (*Bot, error) {
 	config.window = win
 	config.screenCaptureDelayMs = screenCaptureDelayMs
 	config.cvMatchMode = cvMatchMode
-
-	// keysDown is a map of strings that are currently in the 'down' or 'pressed' state.
+	config.detectWorkers = runtime.NumCPU()
+	config.displayScale = detectDisplayScale()
+	config.random = rand.New(globalSource{})
+	config.motionModel = DefaultMotionModel()
+	config.keyTiming = DefaultKeyTiming()
+	config.input = robotgoDriver{}
+	config.holds = make(map[*KeyHold]struct{})
+	config.done = make(chan struct{})
+	config.events = gohookSource{}
+	config.pauseChanged = make(chan struct{})
+	config.limiter = newRateLimiter()
+	config.executor = newExecutor()
+
+	// keysDown is a map of strings that are currently in the 'down' or 'pressed' state to the number of times
+	// they have been pressed, so overlapping holds of the same key do not release it early.
 	// Mouse keys are prefixed with the string `mouse` to be able to distinguish between keyboard's left and right keys
 	// vs the mouses left and right buttons.
-	config.keysDown = make(map[string]bool)
+	config.keysDown = make(map[string]int)
 
 	b := &Bot{}
 	b.config = config
@@ -77,5 +139,55 @@ func (b *Bot) Sleep(s int) {
 
 // (b *Bot) RandomInt generate random integers within a range of min and max values (inclusive).
 func (b *Bot) RandomInt(min, max int) int {
-	return rand.Intn(max-min+1) + min
+	return b.rand().Intn(max-min+1) + min
+}
+
+// (b *Bot) SetRandSource sets the source of every random decision the bot makes, such as the path the cursor takes.
+// Seeding the source makes the bot behave the same way each time it is run. By default the bot uses the global
+// source of the math/rand package.
+func (b *Bot) SetRandSource(src rand.Source) {
+	b.config.botRWMut.Lock()
+	defer b.config.botRWMut.Unlock()
+
+	b.config.random = rand.New(&lockedSource{src: src})
+}
+
+// rand returns the bot's random number generator. It is safe to use from multiple goroutines.
+func (b *Bot) rand() *rand.Rand {
+	b.config.botRWMut.RLock()
+	defer b.config.botRWMut.RUnlock()
+
+	return b.config.random
+}
+
+// lockedSource makes a rand.Source safe to share between goroutines.
+type lockedSource struct {
+	mut sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.src.Seed(seed)
+}
+
+// globalSource draws from the global source of the math/rand package, so seeding it with rand.Seed
+// also affects bots that have not been given a source of their own.
+type globalSource struct{}
+
+func (globalSource) Int63() int64 {
+	return rand.Int63()
+}
+
+func (globalSource) Seed(seed int64) {
+	rand.Seed(seed)
 }
@@ -1,10 +1,12 @@
 package gamebot_test
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/KalebHawkins/gamebot"
 )
@@ -380,3 +382,146 @@ func ExampleBot_UpdateWindow() {
 		b.UpdateWindow()
 	}
 }
+
+func ExampleBot_DetectMany() {
+	procName := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(procName)
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Load the templates to search for.
+	var tmpls []*gamebot.Template
+	for _, name := range []string{"play", "settings", "quit"} {
+		tmpl, err := gamebot.LoadTemplate(filepath.Join("templates", name+".png"))
+		if err != nil {
+			panic(err)
+		}
+		defer tmpl.Close()
+
+		tmpls = append(tmpls, tmpl)
+	}
+
+	// Capture the window once and search for every template at the same time.
+	matches, err := b.DetectMany(context.Background(), b.CaptureWindow(), tmpls)
+	if err != nil {
+		panic(err)
+	}
+
+	if m := matches["play"]; m.Found {
+		fmt.Println("play button found at", m.Location)
+	}
+}
+
+func ExampleBot_ShowDetectedImage() {
+	procName := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(procName)
+
+	if err != nil {
+		panic(err)
+	}
+
+	play, err := gamebot.LoadTemplate(filepath.Join("templates", "play.png"))
+	if err != nil {
+		panic(err)
+	}
+	defer play.Close()
+
+	play.Threshold = 0.9
+
+	// Close the debug window after a minute, or when 'q' is pressed.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	err = b.ShowDetectedImage(ctx, "Debug Window", play)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func ExampleBot_HoldKey() {
+	procName := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(procName)
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Run forward for two seconds without blocking.
+	run, err := b.HoldKey("w", 2*time.Second)
+	if err != nil {
+		panic(err)
+	}
+
+	// Jump while running.
+	b.KeyTap("space")
+
+	// Keep running for another second then wait for the key to be released.
+	run.Extend(time.Second)
+	<-run.Done()
+}
+
+func ExampleBot_Close() {
+	procName := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(procName)
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Release every key if the bot is interrupted, panics or returns.
+	b.CloseOnSignal()
+	defer b.Close()
+	defer b.CloseOnPanic()
+
+	b.HoldKey("w", 2*time.Second)
+}
+
+func ExampleBot_RecordMacro() {
+	procName := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(procName)
+
+	if err != nil {
+		panic(err)
+	}
+	defer b.Close()
+
+	// Record until F8 is pressed.
+	ctx, stop := context.WithCancel(context.Background())
+	b.RegisterHotkey("f8", stop)
+	go b.ListenHotkeys(ctx)
+
+	m, err := b.RecordMacro(ctx)
+	if err != nil {
+		panic(err)
+	}
+	m.Save("routine.json")
+
+	// Replay the routine five times, a little faster and never quite the same.
+	b.PlayMacro(context.Background(), m, &gamebot.PlayOptions{Speed: 1.2, Jitter: 0.1, Loops: 5})
+}
+
+func ExampleNewUinputDriver() {
+	procName := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(procName)
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Send input through virtual devices, including a gamepad, that games see as real hardware.
+	d, err := gamebot.NewUinputDriver(gamebot.UinputOptions{Gamepad: true})
+	if err != nil {
+		panic(err)
+	}
+	defer d.Close()
+	b.SetInputDriver(d)
+	defer b.Close()
+
+	// Run forward for a second then jump.
+	b.SetStick(gamebot.LeftStick, 0, -1)
+	time.Sleep(time.Second)
+	b.SetStick(gamebot.LeftStick, 0, 0)
+	b.TapButton(gamebot.GamepadA)
+}
@@ -1,9 +1,21 @@
 package gamebot
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestWindowFuncs(t *testing.T) {
@@ -93,3 +105,606 @@ func TestBotFuncs(t *testing.T) {
 	})
 
 }
+
+func TestSceneClassifier(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// Two scenes that differ in brightness layout, the second with a noisy "timer" area.
+	menu := image.NewRGBA(image.Rect(0, 0, 90, 80))
+	combat := image.NewRGBA(image.Rect(0, 0, 90, 80))
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 90; x++ {
+			menu.Set(x, y, color.RGBA{uint8(x * 2), 40, 200, 255})
+			combat.Set(x, y, color.RGBA{200, uint8(y * 3), 10, 255})
+		}
+	}
+	timer := image.Rect(0, 0, 30, 10)
+
+	var menuImg, combatImg image.Image = menu, combat
+	c := b.NewSceneClassifier()
+	defer c.Close()
+
+	if err := c.AddReference("menu", &menuImg); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if err := c.AddReference("combat", &combatImg, timer); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	t.Run("Test SceneClassifier.Classify", func(t *testing.T) {
+		frame := image.NewRGBA(combat.Bounds())
+		draw.Draw(frame, frame.Bounds(), combat, image.Point{}, draw.Src)
+		draw.Draw(frame, timer, image.NewUniform(color.White), image.Point{}, draw.Src)
+
+		var img image.Image = frame
+		label, confidence, err := c.Classify(&img)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if label != "combat" {
+			t.Errorf("expected combat, got %s (%f)", label, confidence)
+		}
+	})
+
+	t.Run("Test SceneClassifier.Classify unknown", func(t *testing.T) {
+		c.SetThreshold(1.1)
+		defer c.SetThreshold(sceneThreshold)
+
+		label, _, err := c.Classify(&menuImg)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if label != UnknownScene {
+			t.Errorf("expected %s, got %s", UnknownScene, label)
+		}
+	})
+
+	t.Run("Test SceneClassifier.Classify key templates sharing a name", func(t *testing.T) {
+		// Both scenes have an "ok" button, as if loaded from menu/ok.png and combat/ok.png.
+		key := image.Rect(50, 40, 70, 60)
+		menuOK, err := NewTemplate("ok", menu.SubImage(key))
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		defer menuOK.Close()
+		combatOK, err := NewTemplate("ok", combat.SubImage(key))
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		defer combatOK.Close()
+
+		c := b.NewSceneClassifier()
+		defer c.Close()
+		if err := c.AddReference("menu", &menuImg); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if err := c.AddReference("combat", &combatImg, timer); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		c.AddKeyTemplate("menu", menuOK)
+		c.AddKeyTemplate("combat", combatOK)
+
+		label, confidence, err := c.Classify(&combatImg)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if label != "combat" {
+			t.Errorf("expected combat, got %s (%f)", label, confidence)
+		}
+	})
+
+	t.Run("Test SceneClassifier.Classify missing key template", func(t *testing.T) {
+		// A checkerboard that appears in neither scene.
+		checker := image.NewRGBA(image.Rect(0, 0, 20, 20))
+		for y := 0; y < 20; y++ {
+			for x := 0; x < 20; x++ {
+				if (x/4+y/4)%2 == 0 {
+					checker.Set(x, y, color.White)
+				} else {
+					checker.Set(x, y, color.Black)
+				}
+			}
+		}
+		missing, err := NewTemplate("missing", checker)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		defer missing.Close()
+
+		c := b.NewSceneClassifier()
+		defer c.Close()
+		if err := c.AddReference("combat", &combatImg, timer); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		c.AddKeyTemplate("combat", missing)
+
+		label, confidence, err := c.Classify(&combatImg)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if label != UnknownScene || confidence != 0 {
+			t.Errorf("expected %s with confidence 0, got %s (%f)", UnknownScene, label, confidence)
+		}
+	})
+}
+
+func TestKeyParsing(t *testing.T) {
+	chords := []struct {
+		chord string
+		want  []Key
+	}{
+		{"a", []Key{"a"}},
+		{"ctrl+shift+s", []Key{KeyCtrl, KeyShift, "s"}},
+		{"+", []Key{"+"}},
+		{"shift++", []Key{KeyShift, "+"}},
+		{"Control+Return", []Key{KeyCtrl, KeyEnter}},
+	}
+
+	for _, tt := range chords {
+		t.Run("Test parseChord "+tt.chord, func(t *testing.T) {
+			v, err := parseChord(tt.chord)
+			if err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+			if !reflect.DeepEqual(v, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, v)
+			}
+		})
+	}
+
+	t.Run("Test parseChord unknown key", func(t *testing.T) {
+		if _, err := parseChord("ctrl+enetr"); !errors.Is(err, &UnknownKeyError{}) {
+			t.Errorf("expected %v, got %v", &UnknownKeyError{}, err)
+		}
+	})
+
+	chars := []struct {
+		char rune
+		want []Key
+		ok   bool
+	}{
+		{'a', []Key{"a"}, true},
+		{'A', []Key{KeyShift, "a"}, true},
+		{'7', []Key{"7"}, true},
+		{'&', []Key{KeyShift, "7"}, true},
+		{'?', []Key{KeyShift, "/"}, true},
+		{' ', []Key{KeySpace}, true},
+		{'\n', []Key{KeyEnter}, true},
+		{'é', nil, false},
+	}
+
+	for _, tt := range chars {
+		t.Run("Test charKeys "+string(tt.char), func(t *testing.T) {
+			v, ok := charKeys(tt.char)
+			if ok != tt.ok || !reflect.DeepEqual(v, tt.want) {
+				t.Errorf("expected %v %t, got %v %t", tt.want, tt.ok, v, ok)
+			}
+		})
+	}
+
+	t.Run("Test keyNeighbours", func(t *testing.T) {
+		want := []Key{"f", "h"}
+		if v := keyNeighbours("g"); !reflect.DeepEqual(v, want) {
+			t.Errorf("expected %v, got %v", want, v)
+		}
+		if v := keyNeighbours("space"); len(v) != 0 {
+			t.Errorf("expected no neighbours, got %v", v)
+		}
+	})
+}
+
+func TestDurationRange(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	d := DurationRange{Min: 50 * time.Millisecond, Max: 150 * time.Millisecond}
+
+	for i := 0; i < 1000; i++ {
+		if v := d.draw(r); v < d.Min || v > d.Max {
+			t.Fatalf("expected a duration between %v and %v, got %v", d.Min, d.Max, v)
+		}
+	}
+
+	if v := (DurationRange{Min: time.Second}).draw(r); v != time.Second {
+		t.Errorf("expected %v, got %v", time.Second, v)
+	}
+}
+
+func TestCloseOnSignal(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	driver := NewFakeDriver()
+	b.SetInputDriver(driver)
+
+	exited := make(chan int, 1)
+	exit = func(code int) { exited <- code }
+	defer func() { exit = os.Exit }()
+
+	b.PressKey(KeyCtrl)
+	if _, err := b.HoldKey("w", time.Hour); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	ch := make(chan os.Signal, 1)
+	go b.closeOnSignal(ch)
+	ch <- os.Interrupt
+
+	if code := <-exited; code != 1 {
+		t.Errorf("expected exit status 1, got %d", code)
+	}
+	if v := driver.Down(); len(v) != 0 {
+		t.Errorf("expected no keys down, got %v", v)
+	}
+
+	select {
+	case <-b.config.done:
+	default:
+		t.Errorf("expected the bot to be closed")
+	}
+}
+
+func TestHoldKeyExpiry(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	driver := NewFakeDriver()
+	b.SetInputDriver(driver)
+
+	for i := 0; i < 100; i++ {
+		h, err := b.HoldKey("w", 0)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		<-h.Done()
+	}
+
+	b.config.botRWMut.RLock()
+	n := len(b.config.holds)
+	b.config.botRWMut.RUnlock()
+	if n != 0 {
+		t.Errorf("expected no holds left, got %d", n)
+	}
+	if v := driver.Down(); len(v) != 0 {
+		t.Errorf("expected no keys down, got %v", v)
+	}
+}
+
+// fakeFocusWindow is a focusWindow whose focus is set by the test.
+type fakeFocusWindow struct {
+	mut sync.Mutex
+
+	active bool
+	// activates is true if SetActive gives the window focus.
+	activates bool
+	activated int
+}
+
+func (w *fakeFocusWindow) IsActive() bool {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	return w.active
+}
+
+func (w *fakeFocusWindow) SetActive() {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	w.activated++
+	w.active = w.active || w.activates
+}
+
+func (w *fakeFocusWindow) setActive(active bool) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	w.active = active
+}
+
+func TestFocusGuard(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	driver := NewFakeDriver()
+	b.SetInputDriver(driver)
+	b.SetDisplayScale(1)
+
+	b.config.botRWMut.Lock()
+	b.config.window = &window{position: postiion{x: 100, y: 100}, size: size{w: 200, h: 150}}
+	b.config.botRWMut.Unlock()
+
+	focus := &fakeFocusWindow{}
+	b.config.focusWindow = focus
+
+	if want, got := image.Rect(100, 100, 300, 250), b.WindowBounds(); got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	t.Run("Test FocusOff", func(t *testing.T) {
+		if err := b.KeyTap("a"); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+	})
+
+	t.Run("Test FocusReject", func(t *testing.T) {
+		b.SetFocusGuard(FocusGuard{Policy: FocusReject})
+		driver.Reset()
+
+		if err := b.KeyTap("a"); !errors.Is(err, ErrWindowNotFocused) {
+			t.Errorf("expected %v, got %v", ErrWindowNotFocused, err)
+		}
+		if err := b.TypeText(context.Background(), "é"); !errors.Is(err, ErrWindowNotFocused) {
+			t.Errorf("expected %v, got %v", ErrWindowNotFocused, err)
+		}
+		if v := driver.Events(); len(v) != 0 {
+			t.Errorf("expected no input, got %v", v)
+		}
+	})
+
+	t.Run("Test FocusActivate", func(t *testing.T) {
+		b.SetFocusGuard(FocusGuard{Policy: FocusActivate, Timeout: 100 * time.Millisecond})
+
+		if err := b.KeyTap("a"); !errors.Is(err, ErrWindowNotFocused) {
+			t.Errorf("expected %v, got %v", ErrWindowNotFocused, err)
+		}
+
+		focus.activates = true
+		if err := b.KeyTap("a"); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if focus.activated != 2 {
+			t.Errorf("expected %v, got %v", 2, focus.activated)
+		}
+	})
+
+	t.Run("Test FocusWait", func(t *testing.T) {
+		b.SetFocusGuard(FocusGuard{Policy: FocusWait})
+		focus.setActive(false)
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			focus.setActive(true)
+		}()
+
+		start := time.Now()
+		if err := b.KeyTap("a"); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+			t.Errorf("expected to wait for focus, waited %v", elapsed)
+		}
+	})
+
+	t.Run("Test pausing while waiting for focus", func(t *testing.T) {
+		b.SetFocusGuard(FocusGuard{Policy: FocusWait})
+		focus.setActive(false)
+		defer focus.setActive(true)
+
+		b.Pause()
+		if err := b.PressKey("a"); !errors.Is(err, ErrBotPaused) {
+			t.Errorf("expected %v, got %v", ErrBotPaused, err)
+		}
+		b.Resume()
+
+		errs := make(chan error, 1)
+		go func() {
+			errs <- b.PressKey("a")
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+		b.Pause()
+		defer b.Resume()
+
+		select {
+		case err := <-errs:
+			if !errors.Is(err, ErrBotPaused) {
+				t.Errorf("expected %v, got %v", ErrBotPaused, err)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("expected pausing the bot to stop the wait for focus")
+		}
+	})
+
+	t.Run("Test points outside the window", func(t *testing.T) {
+		b.SetFocusGuard(FocusGuard{Policy: FocusReject})
+		driver.Reset()
+
+		b.SetCursor(50, 50)
+		b.MoveCursor(400, 120)
+		if v := driver.Events(); len(v) != 0 {
+			t.Errorf("expected no input, got %v", v)
+		}
+
+		b.SetCursor(150, 150)
+		if x, y := b.MousePosition(); x != 150 || y != 150 {
+			t.Errorf("expected %v, got %v", image.Pt(150, 150), image.Pt(x, y))
+		}
+		if err := b.MousePress(Left); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		b.MouseRelease(Left)
+
+		driver.Move(10, 10)
+		if err := b.MousePress(Left); !errors.Is(err, &PointOutsideWindowError{}) {
+			t.Errorf("expected %v, got %v", &PointOutsideWindowError{}, err)
+		}
+		if err := b.Scroll(WheelDown, 1); !errors.Is(err, &PointOutsideWindowError{}) {
+			t.Errorf("expected %v, got %v", &PointOutsideWindowError{}, err)
+		}
+
+		b.SetCursor(150, 150)
+		driver.Reset()
+		if err := b.ScrollAt(image.Pt(400, 120), WheelDown, 1); !errors.Is(err, &PointOutsideWindowError{}) {
+			t.Errorf("expected %v, got %v", &PointOutsideWindowError{}, err)
+		}
+		if v := driver.Events(); len(v) != 0 {
+			t.Errorf("expected no input, got %v", v)
+		}
+	})
+}
+
+// decodeUinputEvents decodes the events written to a uinput device.
+func decodeUinputEvents(t *testing.T, buf *bytes.Buffer) []uinputEvent {
+	t.Helper()
+
+	data := buf.Bytes()
+	if len(data)%uinputEventSize != 0 {
+		t.Fatalf("expected whole events, got %d bytes", len(data))
+	}
+
+	var events []uinputEvent
+	for ; len(data) > 0; data = data[uinputEventSize:] {
+		for _, b := range data[:uinputTimevalSize] {
+			if b != 0 {
+				t.Fatalf("expected an empty timestamp, got %v", data[:uinputTimevalSize])
+			}
+		}
+		events = append(events, uinputEvent{
+			typ:   binary.LittleEndian.Uint16(data[uinputTimevalSize:]),
+			code:  binary.LittleEndian.Uint16(data[uinputTimevalSize+2:]),
+			value: int32(binary.LittleEndian.Uint32(data[uinputTimevalSize+4:])),
+		})
+	}
+	buf.Reset()
+
+	return events
+}
+
+func TestUinputDriver(t *testing.T) {
+	var keyboard, mouse, gamepad bytes.Buffer
+	cursor := func() (int, int) { return 5, 5 }
+	d := NewUinputDriverFromDevices(
+		UinputDevices{Keyboard: &keyboard, Mouse: &mouse, Gamepad: &gamepad},
+		UinputOptions{CursorPosition: cursor},
+	)
+	syn := uinputEvent{typ: evSyn, code: synReport}
+
+	if strconv.IntSize == 64 && uinputEventSize != 24 {
+		t.Errorf("expected %v, got %v", 24, uinputEventSize)
+	}
+
+	tests := []struct {
+		name string
+		send func() error
+		buf  *bytes.Buffer
+		want []uinputEvent
+	}{
+		{"KeyDown", func() error { return d.KeyDown("enter") }, &keyboard,
+			[]uinputEvent{{evKey, 28, 1}, syn}},
+		{"KeyDown shifted", func() error { return d.KeyDown("A") }, &keyboard,
+			[]uinputEvent{{evKey, 42, 1}, {evKey, 30, 1}, syn}},
+		{"KeyUp shifted", func() error { return d.KeyUp("A") }, &keyboard,
+			[]uinputEvent{{evKey, 30, 0}, {evKey, 42, 0}, syn}},
+		{"MouseDown", func() error { return d.MouseDown("center") }, &mouse,
+			[]uinputEvent{{evKey, btnMiddle, 1}, syn}},
+		{"Move", func() error { return d.Move(10, 20) }, &mouse,
+			[]uinputEvent{{evAbs, absX, 10}, {evAbs, absY, 20}, syn}},
+		{"Scroll", func() error { return d.Scroll(1, -2) }, &mouse,
+			[]uinputEvent{{evRel, relWheel, -2}, {evRel, relHWheel, -1}, syn}},
+		{"SetStick", func() error { return d.SetStick(RightStick, 1, -1) }, &gamepad,
+			[]uinputEvent{{evAbs, absRX, 32767}, {evAbs, absRY, -32768}, syn}},
+		{"SetTrigger", func() error { return d.SetTrigger(LeftTrigger, 0.5) }, &gamepad,
+			[]uinputEvent{{evAbs, absZ, 128}, syn}},
+		{"ButtonDown", func() error { return d.ButtonDown(GamepadA) }, &gamepad,
+			[]uinputEvent{{evKey, 0x130, 1}, syn}},
+		{"ButtonDown d-pad", func() error { return d.ButtonDown(GamepadDPadUp) }, &gamepad,
+			[]uinputEvent{{evAbs, absHat0Y, -1}, syn}},
+		{"ButtonDown opposite d-pad", func() error { return d.ButtonDown(GamepadDPadDown) }, &gamepad,
+			[]uinputEvent{{evAbs, absHat0Y, 1}, syn}},
+		{"ButtonUp replaced d-pad", func() error { return d.ButtonUp(GamepadDPadUp) }, &gamepad,
+			[]uinputEvent{{evAbs, absHat0Y, 1}, syn}},
+		{"ButtonUp d-pad", func() error { return d.ButtonUp(GamepadDPadDown) }, &gamepad,
+			[]uinputEvent{{evAbs, absHat0Y, 0}, syn}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.send(); err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+			if got := decodeUinputEvents(t, tt.buf); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("Test relative mouse", func(t *testing.T) {
+		rel := NewUinputDriverFromDevices(UinputDevices{Keyboard: &keyboard, Mouse: &mouse},
+			UinputOptions{RelativeMouse: true, CursorPosition: cursor})
+
+		if err := rel.Move(10, 20); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		want := []uinputEvent{{evRel, relX, 5}, {evRel, relY, 15}, syn}
+		if got := decodeUinputEvents(t, &mouse); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+
+		if rel.HasGamepad() {
+			t.Errorf("expected a driver without a gamepad device to have no gamepad")
+		}
+	})
+
+	t.Run("Test unsupported input", func(t *testing.T) {
+		if err := d.KeyDown("mouseleft"); err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+		if err := d.Type("é"); err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+	})
+
+	t.Run("Test bot gamepad", func(t *testing.T) {
+		proc := filepath.Base(os.Args[0])
+		b, err := NewBot(proc)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+
+		b.SetInputDriver(NewFakeDriver())
+		if err := b.PressButton(GamepadA); !errors.Is(err, ErrNoGamepad) {
+			t.Errorf("expected %v, got %v", ErrNoGamepad, err)
+		}
+
+		b.SetInputDriver(d)
+		if err := b.PressButton("z"); !errors.Is(err, &UnknownKeyError{}) {
+			t.Errorf("expected %v, got %v", &UnknownKeyError{}, err)
+		}
+		if err := b.PressButton(GamepadA); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if err := b.SetStick(LeftStick, -2, 0.5); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		want := []uinputEvent{{evKey, 0x130, 1}, syn, {evAbs, absX, -32768}, {evAbs, absY, 16384}, syn}
+		if got := decodeUinputEvents(t, &gamepad); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+
+		if err := b.ReleaseAll(); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		want = []uinputEvent{
+			{evKey, 0x130, 0}, syn,
+			{evAbs, absX, 0}, {evAbs, absY, 0}, syn,
+			{evAbs, absRX, 0}, {evAbs, absRY, 0}, syn,
+			{evAbs, absZ, 0}, syn,
+			{evAbs, absRZ, 0}, syn,
+		}
+		if got := decodeUinputEvents(t, &gamepad); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+}
@@ -1,9 +1,25 @@
 package gamebot_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"math"
+	"math/rand"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/KalebHawkins/gamebot"
 )
@@ -32,3 +48,1850 @@ func TestNewBot(t *testing.T) {
 		t.Errorf("expected window size %d, %d, got %d, %d", want, want, sizeW, sizeH)
 	}
 }
+
+// noiseImage returns an image filled with random pixels so every sub-image of it is unique.
+func noiseImage(w, h int, seed int64) *image.RGBA {
+	r := rand.New(rand.NewSource(seed))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(r.Intn(256)), uint8(r.Intn(256)), uint8(r.Intn(256)), 255})
+		}
+	}
+	return img
+}
+
+// cropImage copies the area `rect` of `img` into a new image.
+func cropImage(img image.Image, rect image.Rectangle) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, rect.Min, draw.Src)
+	return dst
+}
+
+func TestDetectMany(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	var frame image.Image = noiseImage(64, 64, 1)
+	want := map[string]image.Point{
+		"a": image.Pt(0, 0),
+		"b": image.Pt(10, 20),
+		"c": image.Pt(40, 5),
+		"d": image.Pt(50, 50),
+	}
+
+	var tmpls []*gamebot.Template
+	for name, pt := range want {
+		tmpl, err := gamebot.NewTemplate(name, cropImage(frame, image.Rect(pt.X, pt.Y, pt.X+8, pt.Y+8)))
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		defer tmpl.Close()
+
+		tmpls = append(tmpls, tmpl)
+	}
+
+	t.Run("Test bot.DetectMany", func(t *testing.T) {
+		b.SetDetectWorkers(2)
+		matches, err := b.DetectMany(context.Background(), &frame, tmpls)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+
+		if len(matches) != len(want) {
+			t.Errorf("expected %d matches, got %d", len(want), len(matches))
+		}
+
+		for name, pt := range want {
+			m := matches[name]
+			if m.Name != name {
+				t.Errorf("expected match name %s, got %s", name, m.Name)
+			}
+			if m.Location != pt {
+				t.Errorf("expected %s at %v, got %v", name, pt, m.Location)
+			}
+			if !m.Found {
+				t.Errorf("expected %s to be found with score %f", name, m.Score)
+			}
+		}
+	})
+
+	t.Run("Test bot.DetectMany cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := b.DetectMany(ctx, &frame, tmpls)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v, got %v", context.Canceled, err)
+		}
+	})
+
+	t.Run("Test bot.DetectMany nil template", func(t *testing.T) {
+		_, err := b.DetectMany(context.Background(), &frame, append([]*gamebot.Template{nil}, tmpls...))
+		if err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	})
+}
+
+func TestBarReader(t *testing.T) {
+	filled := gamebot.ColorRange{Min: color.RGBA{150, 0, 0, 255}, Max: color.RGBA{255, 60, 60, 255}}
+	empty := gamebot.ColorRange{Min: color.RGBA{20, 20, 20, 255}, Max: color.RGBA{70, 70, 70, 255}}
+
+	// bar draws a 100x10 bar at 10, 10 that is 60% full. The filled part is a red gradient
+	// and a strip of white "text" is drawn across the middle of the bar.
+	bar := func(orientation gamebot.BarOrientation) image.Image {
+		img := image.NewRGBA(image.Rect(0, 0, 120, 120))
+		length := 100
+		for i := 0; i < length; i++ {
+			for j := 0; j < 10; j++ {
+				c := color.RGBA{40, 40, 40, 255}
+				if i < 60 {
+					c = color.RGBA{uint8(160 + i), 20, 20, 255}
+				}
+				if j >= 4 && j < 6 && i > 40 && i < 80 {
+					c = color.RGBA{255, 255, 255, 255}
+				}
+
+				var x, y int
+				switch orientation {
+				case gamebot.LeftToRight:
+					x, y = 10+i, 10+j
+				case gamebot.RightToLeft:
+					x, y = 10+length-1-i, 10+j
+				case gamebot.BottomToTop:
+					x, y = 10+j, 10+length-1-i
+				case gamebot.TopToBottom:
+					x, y = 10+j, 10+i
+				}
+				img.Set(x, y, c)
+			}
+		}
+		return img
+	}
+
+	tests := []struct {
+		name        string
+		orientation gamebot.BarOrientation
+		region      image.Rectangle
+	}{
+		{"left to right", gamebot.LeftToRight, image.Rect(10, 10, 110, 20)},
+		{"right to left", gamebot.RightToLeft, image.Rect(10, 10, 110, 20)},
+		{"bottom to top", gamebot.BottomToTop, image.Rect(10, 10, 20, 110)},
+		{"top to bottom", gamebot.TopToBottom, image.Rect(10, 10, 20, 110)},
+	}
+
+	for _, tt := range tests {
+		t.Run("Test BarReader.Read "+tt.name, func(t *testing.T) {
+			img := bar(tt.orientation)
+			r := gamebot.NewBarReader(tt.region, tt.orientation, filled, empty)
+
+			v, err := r.Read(&img)
+			if err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+			if v != 0.6 {
+				t.Errorf("expected %f, got %f", 0.6, v)
+			}
+		})
+	}
+
+	t.Run("Test BarReader.Read scaled", func(t *testing.T) {
+		// The region was measured on a window twice the size of the frame.
+		img := bar(gamebot.LeftToRight)
+		r := gamebot.NewBarReader(image.Rect(20, 20, 220, 40), gamebot.LeftToRight, filled, empty)
+		r.SetReferenceSize(240, 240)
+
+		v, err := r.Read(&img)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if v != 0.6 {
+			t.Errorf("expected %f, got %f", 0.6, v)
+		}
+	})
+
+	t.Run("Test BarReader.Read outside frame", func(t *testing.T) {
+		img := bar(gamebot.LeftToRight)
+		r := gamebot.NewBarReader(image.Rect(200, 200, 300, 210), gamebot.LeftToRight, filled, empty)
+
+		if _, err := r.Read(&img); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	})
+}
+
+func TestLayout(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	src := noiseImage(64, 64, 2)
+	anchorImg := cropImage(src, image.Rect(20, 10, 30, 20))
+	anchor, err := gamebot.NewTemplate("anchor", anchorImg)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	defer anchor.Close()
+	anchor.Threshold = 0.9
+
+	l := b.NewLayout(anchor)
+	l.AddPoint("sell", image.Pt(5, 5))
+	l.AddRegion("inventory", image.Rect(0, 12, 20, 30))
+
+	t.Run("Test Layout.Point", func(t *testing.T) {
+		var frame image.Image = src
+		if err := l.ResolveFrame(&frame); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+
+		pt, err := l.Point("sell")
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if want := image.Pt(25, 15); pt != want {
+			t.Errorf("expected %v, got %v", want, pt)
+		}
+
+		rect, err := l.Region("inventory")
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if want := image.Rect(20, 22, 40, 40); rect != want {
+			t.Errorf("expected %v, got %v", want, rect)
+		}
+	})
+
+	t.Run("Test Layout.ResolveFrame anchor moved", func(t *testing.T) {
+		moved := noiseImage(64, 64, 3)
+		draw.Draw(moved, image.Rect(40, 40, 50, 50), anchorImg, image.Point{}, draw.Src)
+
+		var frame image.Image = moved
+		if err := l.ResolveFrame(&frame); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+
+		pt, _ := l.Point("sell")
+		if want := image.Pt(45, 45); pt != want {
+			t.Errorf("expected %v, got %v", want, pt)
+		}
+	})
+
+	t.Run("Test Layout.Point unknown", func(t *testing.T) {
+		_, err := l.Point("buy")
+		if !errors.Is(err, &gamebot.LayoutElementNotFoundError{}) {
+			t.Errorf("expected LayoutElementNotFoundError, got %v", err)
+		}
+	})
+}
+
+func TestImageHash(t *testing.T) {
+	// Brightness increases left to right.
+	gradient := image.NewGray(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			gradient.SetGray(x, y, color.Gray{uint8(x * 4)})
+		}
+	}
+
+	t.Run("Test DifferenceHash", func(t *testing.T) {
+		if v := gamebot.DifferenceHash(gradient); v != gamebot.ImageHash(^uint64(0)) {
+			t.Errorf("expected %x, got %x", ^uint64(0), v)
+		}
+	})
+
+	algorithms := []struct {
+		name      string
+		algorithm gamebot.HashAlgorithm
+	}{
+		{"average", gamebot.AverageHashAlgorithm},
+		{"difference", gamebot.DifferenceHashAlgorithm},
+		{"perceptual", gamebot.PerceptualHashAlgorithm},
+	}
+
+	for _, a := range algorithms {
+		t.Run("Test HashAlgorithm.Hash "+a.name, func(t *testing.T) {
+			img := noiseImage(64, 64, 4)
+			other := noiseImage(64, 64, 5)
+
+			if d := a.algorithm.Hash(img).Distance(a.algorithm.Hash(cropImage(img, img.Bounds()))); d != 0 {
+				t.Errorf("expected distance 0 for identical images, got %d", d)
+			}
+
+			if d := a.algorithm.Hash(gradient).Distance(a.algorithm.Hash(other)); d == 0 {
+				t.Errorf("expected non zero distance for different images, got %d", d)
+			}
+		})
+	}
+}
+
+func TestFingerprintStore(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	s := b.NewFingerprintStore(gamebot.DifferenceHashAlgorithm)
+	panel := image.Rect(0, 0, 32, 32)
+
+	var frame image.Image = noiseImage(64, 64, 6)
+	changed := noiseImage(64, 64, 6)
+	draw.Draw(changed, image.Rect(32, 32, 64, 64), noiseImage(32, 32, 7), image.Point{}, draw.Src)
+	var changedFrame image.Image = changed
+
+	if !s.Changed("panel", &frame, panel, 0) {
+		t.Errorf("expected first call to report a change")
+	}
+
+	if s.Changed("panel", &frame, panel, 0) {
+		t.Errorf("expected identical frame to report no change")
+	}
+
+	// Only the area outside of the panel changed.
+	if s.Changed("panel", &changedFrame, panel, 0) {
+		t.Errorf("expected change outside of the region to be ignored")
+	}
+
+	if !s.Matches("panel", &frame, 0) {
+		t.Errorf("expected frame to match the stored fingerprint")
+	}
+
+	if s.Matches("missing", &frame, 0) {
+		t.Errorf("expected missing fingerprint not to match")
+	}
+}
+
+func TestImageExport(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	var frame image.Image = noiseImage(64, 48, 8)
+
+	t.Run("Test bot.SaveImage", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "frame.png")
+		if err := b.SaveImage(path, &frame); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+
+		img, err := b.OpenImage(path)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if (*img).Bounds() != frame.Bounds() {
+			t.Errorf("expected bounds %v, got %v", frame.Bounds(), (*img).Bounds())
+		}
+
+		if err := b.SaveImage(filepath.Join(t.TempDir(), "frame.gif"), &frame); err == nil {
+			t.Errorf("expected error for unsupported format, got nil")
+		}
+	})
+
+	t.Run("Test bot.Crop", func(t *testing.T) {
+		crop := b.Crop(&frame, image.Rect(10, 10, 30, 20))
+		if want := image.Rect(0, 0, 20, 10); (*crop).Bounds() != want {
+			t.Errorf("expected bounds %v, got %v", want, (*crop).Bounds())
+		}
+		if (*crop).At(0, 0) != frame.At(10, 10) {
+			t.Errorf("expected %v, got %v", frame.At(10, 10), (*crop).At(0, 0))
+		}
+	})
+
+	t.Run("Test bot.Annotate", func(t *testing.T) {
+		box := gamebot.Box{Label: "inventory", Rect: image.Rect(5, 20, 40, 40), Color: color.RGBA{0, 255, 0, 255}}
+		m := gamebot.Match{Name: "button", Score: 0.95, Location: image.Pt(30, 5), Size: image.Pt(10, 10)}
+
+		out, err := b.Annotate(&frame, box, m, gamebot.Marker{Point: image.Pt(50, 40)})
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if (*out).Bounds().Size() != frame.Bounds().Size() {
+			t.Errorf("expected size %v, got %v", frame.Bounds().Size(), (*out).Bounds().Size())
+		}
+
+		r, g, bl, _ := (*out).At(5, 30).RGBA()
+		if r>>8 != 0 || g>>8 != 255 || bl>>8 != 0 {
+			t.Errorf("expected box edge to be green, got %d, %d, %d", r>>8, g>>8, bl>>8)
+		}
+	})
+}
+
+func TestDebugServer(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	s := b.NewDebugServer("localhost:0")
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	var frame image.Image = noiseImage(64, 48, 9)
+	m := gamebot.Match{Name: "button", Score: 0.95, Location: image.Pt(30, 5), Size: image.Pt(10, 10)}
+	if err := s.Publish(&frame, m); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	t.Run("Test DebugServer scores", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/scores")
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		defer resp.Body.Close()
+
+		var scores []gamebot.DebugScore
+		if err := json.NewDecoder(resp.Body).Decode(&scores); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+
+		if len(scores) != 1 || scores[0].Name != "button" || scores[0].Score != m.Score {
+			t.Errorf("expected score for button, got %v", scores)
+		}
+	})
+
+	t.Run("Test DebugServer stream", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/stream", nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		defer resp.Body.Close()
+
+		mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		if err != nil || mediaType != "multipart/x-mixed-replace" {
+			t.Fatalf("expected multipart/x-mixed-replace, got %s", resp.Header.Get("Content-Type"))
+		}
+
+		part, err := multipart.NewReader(resp.Body, params["boundary"]).NextPart()
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+
+		img, err := jpeg.Decode(part)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if img.Bounds().Size() != frame.Bounds().Size() {
+			t.Errorf("expected frame size %v, got %v", frame.Bounds().Size(), img.Bounds().Size())
+		}
+	})
+}
+
+func TestTemplate(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	t.Run("Test LoadTemplate", func(t *testing.T) {
+		tmpl, err := gamebot.LoadTemplate(filepath.Join("testdata", "test.png"))
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		defer tmpl.Close()
+
+		if tmpl.Name != "test" {
+			t.Errorf("expected name test, got %s", tmpl.Name)
+		}
+		if want := image.Pt(26, 21); tmpl.Size() != want {
+			t.Errorf("expected size %v, got %v", want, tmpl.Size())
+		}
+	})
+
+	t.Run("Test LoadTemplateFS", func(t *testing.T) {
+		tmpl, err := gamebot.LoadTemplateFS(os.DirFS("testdata"), "test.png")
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		defer tmpl.Close()
+
+		if tmpl.Name != "test" || tmpl.Path != "test.png" {
+			t.Errorf("expected name test and path test.png, got %s and %s", tmpl.Name, tmpl.Path)
+		}
+	})
+
+	t.Run("Test NewTemplateFromBytes", func(t *testing.T) {
+		data, err := os.ReadFile(filepath.Join("testdata", "test.png"))
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+
+		tmpl, err := gamebot.NewTemplateFromBytes("bytes", data)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		defer tmpl.Close()
+
+		if _, err := gamebot.NewTemplateFromBytes("bad", []byte("not an image")); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	})
+
+	t.Run("Test Template.Region", func(t *testing.T) {
+		// The same patch appears twice, the region limits the search to the second one.
+		src := noiseImage(64, 64, 10)
+		patch := cropImage(src, image.Rect(0, 0, 8, 8))
+		draw.Draw(src, image.Rect(40, 40, 48, 48), patch, image.Point{}, draw.Src)
+		var frame image.Image = src
+
+		tmpl, err := gamebot.NewTemplate("patch", patch)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		defer tmpl.Close()
+		tmpl.Region = image.Rect(32, 32, 64, 64)
+
+		m, err := b.Detect(&frame, tmpl)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if want := image.Pt(40, 40); m.Location != want {
+			t.Errorf("expected %v, got %v", want, m.Location)
+		}
+	})
+
+	t.Run("Test Template.Close", func(t *testing.T) {
+		var frame image.Image = noiseImage(32, 32, 11)
+		tmpl, err := gamebot.NewTemplate("closed", cropImage(frame, image.Rect(0, 0, 8, 8)))
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+
+		if err := tmpl.SetMask(image.NewGray(image.Rect(0, 0, 4, 4))); err == nil {
+			t.Errorf("expected error for mask of the wrong size, got nil")
+		}
+
+		tmpl.Close()
+		if _, err := b.Detect(&frame, tmpl); err == nil {
+			t.Errorf("expected error detecting a closed template, got nil")
+		}
+	})
+}
+
+func TestCoordinateTransform(t *testing.T) {
+	tr := gamebot.CoordinateTransform{Scale: 2, WindowOrigin: image.Pt(100, 50)}
+
+	tests := []struct {
+		name string
+		got  image.Point
+		want image.Point
+	}{
+		{"CaptureToLogical", tr.CaptureToLogical(image.Pt(400, 300)), image.Pt(200, 150)},
+		{"LogicalToCapture", tr.LogicalToCapture(image.Pt(200, 150)), image.Pt(400, 300)},
+		{"WindowToCapture", tr.WindowToCapture(image.Pt(10, 20)), image.Pt(210, 120)},
+		{"CaptureToWindow", tr.CaptureToWindow(image.Pt(210, 120)), image.Pt(10, 20)},
+		{"WindowToLogical", tr.WindowToLogical(image.Pt(10, 20)), image.Pt(105, 60)},
+		{"LogicalToWindow", tr.LogicalToWindow(image.Pt(105, 60)), image.Pt(10, 20)},
+		{"unset scale", gamebot.CoordinateTransform{}.CaptureToLogical(image.Pt(7, 9)), image.Pt(7, 9)},
+	}
+
+	for _, tt := range tests {
+		t.Run("Test CoordinateTransform."+tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, tt.got)
+			}
+		})
+	}
+}
+
+func TestBotDisplayScale(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	b.SetDisplayScale(1.5)
+	if v := b.DisplayScale(); v != 1.5 {
+		t.Errorf("expected display scale %f, got %f", 1.5, v)
+	}
+
+	wx, wy := b.Window().Position()
+	want := image.Pt(int(math.Round(float64(wx)*1.5))+10, int(math.Round(float64(wy)*1.5))+10)
+	if v := b.WindowToScreen(image.Pt(10, 10)); v != want {
+		t.Errorf("expected %v, got %v", want, v)
+	}
+	if v := b.ScreenToWindow(want); v != image.Pt(10, 10) {
+		t.Errorf("expected %v, got %v", image.Pt(10, 10), v)
+	}
+}
+
+func TestMotionModels(t *testing.T) {
+	from, to := image.Pt(10, 20), image.Pt(610, 420)
+
+	models := []struct {
+		name  string
+		model gamebot.MotionModel
+	}{
+		{"WindMouse", &gamebot.WindMouse{}},
+		{"BezierMotion", &gamebot.BezierMotion{}},
+		{"FittsMotion", &gamebot.FittsMotion{}},
+		{"OvershootMotion", &gamebot.OvershootMotion{Probability: 1}},
+		{"JitterMotion", &gamebot.JitterMotion{}},
+		{"DefaultMotionModel", gamebot.DefaultMotionModel()},
+	}
+
+	for _, tt := range models {
+		t.Run("Test "+tt.name, func(t *testing.T) {
+			path := tt.model.Path(from, to, rand.New(rand.NewSource(1)))
+			if len(path) < 2 {
+				t.Fatalf("expected a path of at least 2 points, got %d", len(path))
+			}
+			if v := path[len(path)-1].Point; v != to {
+				t.Errorf("expected path to end at %v, got %v", to, v)
+			}
+
+			again := tt.model.Path(from, to, rand.New(rand.NewSource(1)))
+			if len(again) != len(path) {
+				t.Fatalf("expected the same seed to give %d points, got %d", len(path), len(again))
+			}
+			for i := range path {
+				if path[i] != again[i] {
+					t.Fatalf("expected the same seed to give point %v at %d, got %v", path[i], i, again[i])
+				}
+			}
+		})
+	}
+
+	t.Run("Test FittsMotion duration", func(t *testing.T) {
+		duration := func(to image.Point, width float64) time.Duration {
+			var total time.Duration
+			for _, p := range (&gamebot.FittsMotion{TargetWidth: width}).Path(from, to, rand.New(rand.NewSource(1))) {
+				total += p.Delay
+			}
+			return total
+		}
+
+		near, far := duration(image.Pt(60, 20), 20), duration(image.Pt(1010, 20), 20)
+		if near >= far {
+			t.Errorf("expected a short move (%v) to be quicker than a long move (%v)", near, far)
+		}
+
+		small, large := duration(image.Pt(1010, 20), 5), duration(image.Pt(1010, 20), 200)
+		if large >= small {
+			t.Errorf("expected a large target (%v) to be quicker than a small target (%v)", large, small)
+		}
+	})
+
+	t.Run("Test OvershootMotion overshoots", func(t *testing.T) {
+		path := (&gamebot.OvershootMotion{Probability: 1, Model: &gamebot.BezierMotion{Spread: 0.01}}).Path(image.Pt(0, 0), image.Pt(500, 0), rand.New(rand.NewSource(1)))
+
+		maxX := 0
+		for _, p := range path {
+			if p.X > maxX {
+				maxX = p.X
+			}
+		}
+		if maxX <= 500 {
+			t.Errorf("expected the cursor to pass x 500, got a furthest x of %d", maxX)
+		}
+	})
+}
+
+func TestBotCursorPath(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	b.SetMotionModel(&gamebot.BezierMotion{})
+	if _, ok := b.MotionModel().(*gamebot.BezierMotion); !ok {
+		t.Errorf("expected *gamebot.BezierMotion, got %T", b.MotionModel())
+	}
+
+	b.SetRandSource(rand.NewSource(7))
+	first := b.CursorPath(image.Pt(0, 0), image.Pt(300, 200))
+	b.SetRandSource(rand.NewSource(7))
+	second := b.CursorPath(image.Pt(0, 0), image.Pt(300, 200))
+
+	if len(first) != len(second) {
+		t.Fatalf("expected the same seed to give %d points, got %d", len(first), len(second))
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("expected the same seed to give point %v at %d, got %v", first[i], i, second[i])
+		}
+	}
+
+	b.SetMotionModel(nil)
+	if b.MotionModel() == nil {
+		t.Errorf("expected the default motion model, got nil")
+	}
+}
+
+func TestClickPoint(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	b.SetRandSource(rand.NewSource(1))
+
+	rect := image.Rect(100, 200, 160, 240)
+
+	t.Run("Test ClickPoint stays within the target", func(t *testing.T) {
+		inner := image.Rect(115, 210, 145, 230)
+		central := 0
+		for i := 0; i < 1000; i++ {
+			p := b.ClickPoint(rect)
+			if !p.In(rect) {
+				t.Fatalf("expected %v to be within %v", p, rect)
+			}
+			if p.In(inner) {
+				central++
+			}
+		}
+
+		// The central quarter of the target holds well over a quarter of the clicks.
+		if central < 500 {
+			t.Errorf("expected most clicks near the center, got %d of 1000", central)
+		}
+	})
+
+	t.Run("Test ClickPoint with margin", func(t *testing.T) {
+		inset := rect.Inset(10)
+		for i := 0; i < 1000; i++ {
+			if p := b.ClickPoint(rect, gamebot.WithMargin(10)); !p.In(inset) {
+				t.Fatalf("expected %v to be within %v", p, inset)
+			}
+		}
+
+		want := image.Pt(130, 220)
+		if p := b.ClickPoint(rect, gamebot.WithMargin(50)); p != want {
+			t.Errorf("expected %v for a margin larger than the target, got %v", want, p)
+		}
+	})
+
+	t.Run("Test ClickMatch not found", func(t *testing.T) {
+		err := b.ClickMatch(gamebot.Match{Name: "missing", Score: 0.2}, gamebot.Left)
+		if !errors.Is(err, &gamebot.MatchNotFoundError{}) {
+			t.Errorf("expected %v, got %v", &gamebot.MatchNotFoundError{}, err)
+		}
+	})
+}
+
+func TestGestureCancelled(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t.Run("Test Drag", func(t *testing.T) {
+		err := b.Drag(ctx, image.Pt(10, 10), image.Pt(100, 100), gamebot.Left, nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v, got %v", context.Canceled, err)
+		}
+		if b.IsKeyDown("mouseleft") {
+			t.Errorf("expected mouseleft to be released")
+		}
+	})
+
+	t.Run("Test LongPress", func(t *testing.T) {
+		err := b.LongPress(ctx, image.Pt(10, 10), gamebot.Left, time.Second)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v, got %v", context.Canceled, err)
+		}
+		if b.IsKeyDown("mouseleft") {
+			t.Errorf("expected mouseleft to be released")
+		}
+	})
+
+	// cancelWhileHeld runs `gesture` with a context that is cancelled once the left button is down, then checks that
+	// the button was released.
+	cancelWhileHeld := func(t *testing.T, gesture func(ctx context.Context) error) {
+		driver := gamebot.NewFakeDriver()
+		b.SetInputDriver(driver)
+		b.SetDisplayScale(1)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			for !b.IsKeyDown("mouseleft") {
+				time.Sleep(time.Millisecond)
+			}
+			cancel()
+		}()
+
+		start := time.Now()
+		if err := gesture(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v, got %v", context.Canceled, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("expected the gesture to stop when cancelled, took %v", elapsed)
+		}
+
+		events := driver.Events()
+		if len(events) == 0 || events[len(events)-1] != (gamebot.InputEvent{Action: gamebot.MouseUpAction, Key: string(gamebot.Left)}) {
+			t.Errorf("expected the last event to release %s, got %+v", gamebot.Left, events)
+		}
+		if keys := b.KeysDown(); len(keys) != 0 {
+			t.Errorf("expected no keys down, got %v", keys)
+		}
+		if keys := driver.Down(); len(keys) != 0 {
+			t.Errorf("expected no keys down, got %v", keys)
+		}
+	}
+
+	t.Run("Test Drag cancelled while held", func(t *testing.T) {
+		cancelWhileHeld(t, func(ctx context.Context) error {
+			return b.Drag(ctx, image.Pt(10, 10), image.Pt(100, 100), gamebot.Left, &gamebot.DragOptions{HoldBefore: 5 * time.Second})
+		})
+	})
+
+	t.Run("Test LongPress cancelled while held", func(t *testing.T) {
+		cancelWhileHeld(t, func(ctx context.Context) error {
+			return b.LongPress(ctx, image.Pt(10, 10), gamebot.Left, 5*time.Second)
+		})
+	})
+}
+
+func TestScrollDirection(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	if err := b.Scroll(gamebot.Left, 1); err == nil {
+		t.Errorf("expected error scrolling with %s, got nil", gamebot.Left)
+	}
+	if err := b.ScrollAt(image.Pt(10, 10), gamebot.Left, 1); err == nil {
+		t.Errorf("expected error scrolling with %s, got nil", gamebot.Left)
+	}
+	if _, err := b.ScrollUntilVisible(context.Background(), nil, image.Pt(10, 10), gamebot.Left, 1); err == nil {
+		t.Errorf("expected error scrolling with %s, got nil", gamebot.Left)
+	}
+	if err := b.Scroll(gamebot.WheelDown, 0); err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+}
+
+func TestTypeText(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	timing := gamebot.KeyTiming{
+		Hold:     gamebot.DurationRange{Min: time.Millisecond, Max: 2 * time.Millisecond},
+		Gap:      gamebot.DurationRange{Min: time.Millisecond, Max: 2 * time.Millisecond},
+		TypoRate: 0.5,
+	}
+	b.SetKeyTiming(timing)
+	if v := b.KeyTiming(); v != timing {
+		t.Errorf("expected %v, got %v", timing, v)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := b.TypeText(ctx, "Hello!"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if v := b.KeysDown(); len(v) != 0 {
+		t.Errorf("expected no keys down, got %v", v)
+	}
+}
+
+func TestHoldKey(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	t.Run("Test overlapping holds", func(t *testing.T) {
+		short, err := b.HoldKey("w", 20*time.Millisecond)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		long, err := b.HoldKey("w", 200*time.Millisecond)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+
+		<-short.Done()
+		if !b.IsKeyDown("w") {
+			t.Errorf("expected w to stay down while the longer hold is running")
+		}
+
+		<-long.Done()
+		if b.IsKeyDown("w") {
+			t.Errorf("expected w to be released")
+		}
+	})
+
+	t.Run("Test Extend and Release", func(t *testing.T) {
+		h, err := b.HoldKey("shift+w", 20*time.Millisecond)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		deadline := h.Deadline()
+
+		if !h.Extend(time.Second) {
+			t.Fatalf("expected the hold to be extended")
+		}
+		if v := h.Deadline(); v != deadline.Add(time.Second) {
+			t.Errorf("expected %v, got %v", deadline.Add(time.Second), v)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+		if !b.IsKeyDown("shift") || !b.IsKeyDown("w") {
+			t.Errorf("expected shift and w to be down, got %v", b.KeysDown())
+		}
+
+		h.Release()
+		h.Release()
+		<-h.Done()
+
+		if v := b.KeysDown(); len(v) != 0 {
+			t.Errorf("expected no keys down, got %v", v)
+		}
+		if h.Extend(time.Second) {
+			t.Errorf("expected a released hold not to be extended")
+		}
+	})
+}
+
+func TestReleaseAll(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	driver := gamebot.NewFakeDriver()
+	b.SetInputDriver(driver)
+
+	t.Run("Test ReleaseAll", func(t *testing.T) {
+		b.PressKey("ctrl")
+		b.PressKey("w")
+		b.PressKey("w")
+		b.MousePress(gamebot.Left)
+		hold, err := b.HoldKey("shift", time.Hour)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+
+		want := []string{"ctrl", "mouseleft", "shift", "w"}
+		if v := driver.Down(); !reflect.DeepEqual(v, want) {
+			t.Fatalf("expected %v, got %v", want, v)
+		}
+
+		if err := b.ReleaseAll(); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+		if v := driver.Down(); len(v) != 0 {
+			t.Errorf("expected no keys down, got %v", v)
+		}
+		if v := b.KeysDown(); len(v) != 0 {
+			t.Errorf("expected no keys down, got %v", v)
+		}
+
+		select {
+		case <-hold.Done():
+		default:
+			t.Errorf("expected the hold to be released")
+		}
+	})
+
+	t.Run("Test CloseOnPanic", func(t *testing.T) {
+		func() {
+			defer func() {
+				if r := recover(); r != "boom" {
+					t.Errorf("expected the panic to continue, got %v", r)
+				}
+			}()
+			defer b.CloseOnPanic()
+
+			b.PressKey("ctrl")
+			b.MousePress(gamebot.Right)
+			panic("boom")
+		}()
+
+		if v := driver.Down(); len(v) != 0 {
+			t.Errorf("expected no keys down, got %v", v)
+		}
+	})
+}
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want gamebot.Key
+	}{
+		{"w", "w"},
+		{"W", "W"},
+		{" ", gamebot.KeySpace},
+		{"Enter", gamebot.KeyEnter},
+		{"return", gamebot.KeyEnter},
+		{"esc", gamebot.KeyEscape},
+		{"escape", gamebot.KeyEscape},
+		{"lmb", gamebot.KeyMouseLeft},
+		{"F12", gamebot.KeyF12},
+		{"num_enter", "num_enter"},
+		{"num+", "num+"},
+		{"numpad_5", "numpad_5"},
+		{"audio_rewind", "audio_rewind"},
+		{"lights_kbd_up", "lights_kbd_up"},
+	}
+
+	for _, tt := range tests {
+		t.Run("Test ParseKey "+tt.name, func(t *testing.T) {
+			v, err := gamebot.ParseKey(tt.name)
+			if err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+			if v != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, v)
+			}
+		})
+	}
+
+	for _, name := range []string{"", "enetr", "ctrl+s", "é"} {
+		t.Run("Test ParseKey unknown "+name, func(t *testing.T) {
+			if _, err := gamebot.ParseKey(name); !errors.Is(err, &gamebot.UnknownKeyError{}) {
+				t.Errorf("expected %v, got %v", &gamebot.UnknownKeyError{}, err)
+			}
+		})
+	}
+}
+
+func TestMouseButtonKeys(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	driver := gamebot.NewFakeDriver()
+	b.SetInputDriver(driver)
+
+	tests := []struct {
+		btn gamebot.MouseButton
+		key gamebot.Key
+	}{
+		{gamebot.Left, gamebot.KeyMouseLeft},
+		{gamebot.Right, "rmb"},
+		{gamebot.Center, "mmb"},
+	}
+
+	for _, tt := range tests {
+		t.Run("Test "+string(tt.btn), func(t *testing.T) {
+			driver.Reset()
+
+			if err := b.MousePress(tt.btn); err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+			if !b.IsKeyDown(tt.key) {
+				t.Errorf("expected %s to be down", tt.key)
+			}
+			if err := b.ReleaseKey(tt.key); err != nil {
+				t.Errorf("expected nil error, got %v", err)
+			}
+
+			want := []gamebot.InputEvent{
+				{Action: gamebot.MouseDownAction, Key: string(tt.btn)},
+				{Action: gamebot.MouseUpAction, Key: string(tt.btn)},
+			}
+			if v := driver.Events(); !reflect.DeepEqual(v, want) {
+				t.Errorf("expected %v, got %v", want, v)
+			}
+		})
+	}
+}
+
+func TestPressKeyValidation(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	driver := gamebot.NewFakeDriver()
+	b.SetInputDriver(driver)
+
+	if err := b.PressKey("enetr"); !errors.Is(err, &gamebot.UnknownKeyError{}) {
+		t.Errorf("expected %v, got %v", &gamebot.UnknownKeyError{}, err)
+	}
+	if err := b.KeyTap("ctrl+enetr"); !errors.Is(err, &gamebot.UnknownKeyError{}) {
+		t.Errorf("expected %v, got %v", &gamebot.UnknownKeyError{}, err)
+	}
+	if v := driver.Events(); len(v) != 0 {
+		t.Errorf("expected no input, got %v", v)
+	}
+	if v := b.KeysDown(); len(v) != 0 {
+		t.Errorf("expected no keys down, got %v", v)
+	}
+
+	if err := b.PressKey("lmb"); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if !b.IsKeyDown(gamebot.KeyMouseLeft) {
+		t.Errorf("expected %s to be down", gamebot.KeyMouseLeft)
+	}
+	if err := b.ReleaseKey("return"); err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+	if err := b.ReleaseKey(gamebot.KeyMouseLeft); err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+
+	want := []gamebot.InputEvent{
+		{Action: gamebot.MouseDownAction, Key: "left"},
+		{Action: gamebot.KeyUpAction, Key: "enter"},
+		{Action: gamebot.MouseUpAction, Key: "left"},
+	}
+	if v := driver.Events(); !reflect.DeepEqual(v, want) {
+		t.Errorf("expected %v, got %v", want, v)
+	}
+
+	driver.Reset()
+	if err := b.KeyTap("ctrl+num+"); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	want = []gamebot.InputEvent{
+		{Action: gamebot.KeyDownAction, Key: "ctrl"},
+		{Action: gamebot.KeyDownAction, Key: "num+"},
+		{Action: gamebot.KeyUpAction, Key: "num+"},
+		{Action: gamebot.KeyUpAction, Key: "ctrl"},
+	}
+	if v := driver.Events(); !reflect.DeepEqual(v, want) {
+		t.Errorf("expected %v, got %v", want, v)
+	}
+}
+
+func TestClickErrors(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	driver := gamebot.NewFakeDriver()
+	b.SetInputDriver(driver)
+	b.SetDisplayScale(1)
+
+	b.Pause()
+	if err := b.Click(gamebot.Left, false); !errors.Is(err, gamebot.ErrBotPaused) {
+		t.Errorf("expected %v, got %v", gamebot.ErrBotPaused, err)
+	}
+	if err := b.MoveCursorClick(100, 100, gamebot.Left, false); !errors.Is(err, gamebot.ErrBotPaused) {
+		t.Errorf("expected %v, got %v", gamebot.ErrBotPaused, err)
+	}
+	if err := b.MoveCursorSmoothClick(100, 100, gamebot.Left, true); !errors.Is(err, gamebot.ErrBotPaused) {
+		t.Errorf("expected %v, got %v", gamebot.ErrBotPaused, err)
+	}
+	if v := driver.Events(); len(v) != 0 {
+		t.Errorf("expected no input, got %v", v)
+	}
+
+	b.Resume()
+	if err := b.MoveCursorClick(100, 100, gamebot.Left, false); err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+}
+
+// waitFor fails the test if `cond` does not become true within a couple of seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHotkeys(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	driver := gamebot.NewFakeDriver()
+	b.SetInputDriver(driver)
+	src := gamebot.NewFakeEventSource()
+	b.SetEventSource(src)
+
+	if err := b.RegisterHotkey("ctrl+enetr", func() {}); !errors.Is(err, &gamebot.UnknownKeyError{}) {
+		t.Errorf("expected %v, got %v", &gamebot.UnknownKeyError{}, err)
+	}
+
+	pressed := make(chan struct{}, 10)
+	if err := b.RegisterHotkey("ctrl+k", func() { pressed <- struct{}{} }); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	shifted := make(chan string, 10)
+	for _, chord := range []string{"ctrl+P", "ctrl+!"} {
+		chord := chord
+		if err := b.RegisterHotkey(chord, func() { shifted <- chord }); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+	}
+	if err := b.RegisterControlHotkeys(gamebot.DefaultControlHotkeys()); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	listening := make(chan error, 1)
+	go func() {
+		listening <- b.ListenHotkeys(context.Background())
+	}()
+
+	t.Run("Test RegisterHotkey", func(t *testing.T) {
+		src.Tap("k")
+		src.Send(gamebot.InputEvent{Action: gamebot.KeyDownAction, Key: string(gamebot.KeyRCtrl)})
+		src.Tap("k")
+		src.Send(gamebot.InputEvent{Action: gamebot.KeyUpAction, Key: string(gamebot.KeyRCtrl)})
+
+		select {
+		case <-pressed:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected the hotkey to be pressed")
+		}
+
+		waitFor(t, "k to be handled", func() bool { return len(pressed) == 0 })
+		select {
+		case <-pressed:
+			t.Errorf("expected the hotkey to be pressed once")
+		default:
+		}
+	})
+
+	t.Run("Test shifted characters", func(t *testing.T) {
+		src.Send(gamebot.InputEvent{Action: gamebot.KeyDownAction, Key: string(gamebot.KeyLCtrl)})
+		src.Send(gamebot.InputEvent{Action: gamebot.KeyDownAction, Key: string(gamebot.KeyRShift)})
+		src.Tap("p")
+		src.Tap("1")
+		src.Send(gamebot.InputEvent{Action: gamebot.KeyUpAction, Key: string(gamebot.KeyRShift)})
+		src.Send(gamebot.InputEvent{Action: gamebot.KeyUpAction, Key: string(gamebot.KeyLCtrl)})
+
+		var got []string
+		for len(got) < 2 {
+			select {
+			case chord := <-shifted:
+				got = append(got, chord)
+			case <-time.After(2 * time.Second):
+				t.Fatalf("expected both shifted hotkeys to be pressed, got %v", got)
+			}
+		}
+		if want := []string{"ctrl+P", "ctrl+!"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+
+		// Without shift the chord is a different hotkey. Events are handled in order, so once ctrl+k has been
+		// handled so has ctrl+p.
+		src.Send(gamebot.InputEvent{Action: gamebot.KeyDownAction, Key: string(gamebot.KeyCtrl)})
+		src.Tap("p")
+		src.Tap("k")
+		src.Send(gamebot.InputEvent{Action: gamebot.KeyUpAction, Key: string(gamebot.KeyCtrl)})
+		select {
+		case <-pressed:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected ctrl+k to be pressed")
+		}
+		select {
+		case chord := <-shifted:
+			t.Errorf("expected ctrl+p not to press %s", chord)
+		default:
+		}
+	})
+
+	t.Run("Test pause and step", func(t *testing.T) {
+		if err := b.PressKey("w"); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+
+		src.Tap(gamebot.KeyF9)
+		waitFor(t, "the bot to be paused", b.Paused)
+
+		if v := driver.Down(); len(v) != 0 {
+			t.Errorf("expected pausing to release every key, got %v", v)
+		}
+		if err := b.PressKey("w"); !errors.Is(err, gamebot.ErrBotPaused) {
+			t.Errorf("expected %v, got %v", gamebot.ErrBotPaused, err)
+		}
+
+		src.Tap(gamebot.KeyF10)
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := b.WaitWhilePaused(ctx); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if err := b.KeyTap("w"); err != nil {
+			t.Errorf("expected a step to allow input, got %v", err)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := b.WaitWhilePaused(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+		}
+
+		src.Tap(gamebot.KeyF9)
+		waitFor(t, "the bot to be resumed", func() bool { return !b.Paused() })
+		if err := b.WaitWhilePaused(context.Background()); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+	})
+
+	t.Run("Test kill", func(t *testing.T) {
+		if err := b.PressKey("w"); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+
+		src.Send(gamebot.InputEvent{Action: gamebot.KeyDownAction, Key: string(gamebot.KeyCtrl)})
+		src.Tap(gamebot.KeyF12)
+
+		select {
+		case err := <-listening:
+			if err != nil {
+				t.Errorf("expected nil error, got %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected killing the bot to stop the listener")
+		}
+
+		if v := driver.Down(); len(v) != 0 {
+			t.Errorf("expected killing the bot to release every key, got %v", v)
+		}
+		if err := b.PressKey("w"); !errors.Is(err, gamebot.ErrBotKilled) {
+			t.Errorf("expected %v, got %v", gamebot.ErrBotKilled, err)
+		}
+		if err := b.WaitWhilePaused(context.Background()); !errors.Is(err, gamebot.ErrBotKilled) {
+			t.Errorf("expected %v, got %v", gamebot.ErrBotKilled, err)
+		}
+	})
+}
+
+func TestMacro(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	driver := gamebot.NewFakeDriver()
+	b.SetInputDriver(driver)
+	src := gamebot.NewFakeEventSource()
+	b.SetEventSource(src)
+	b.SetDisplayScale(1)
+
+	origin := b.WindowToScreen(image.Pt(0, 0))
+	src.Send(gamebot.InputEvent{Action: gamebot.KeyUpAction, Key: "e"})
+	src.Send(gamebot.InputEvent{Action: gamebot.MoveAction, X: origin.X + 10, Y: origin.Y + 20})
+	src.Send(gamebot.InputEvent{Action: gamebot.KeyDownAction, Key: "w"})
+	src.Send(gamebot.InputEvent{Action: gamebot.KeyDownAction, Key: "w"})
+	src.Send(gamebot.InputEvent{Action: gamebot.KeyUpAction, Key: "w"})
+	src.Send(gamebot.InputEvent{Action: gamebot.MouseDownAction, Key: "left", X: origin.X + 30, Y: origin.Y + 40})
+	src.Send(gamebot.InputEvent{Action: gamebot.MouseUpAction, Key: "left", X: origin.X + 30, Y: origin.Y + 40})
+	src.Send(gamebot.InputEvent{Action: gamebot.ScrollAction, Y: -2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	m, err := b.RecordMacro(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	t.Run("Test RecordMacro", func(t *testing.T) {
+		var got []gamebot.InputEvent
+		for i, e := range m.Events {
+			got = append(got, e.InputEvent)
+			if i > 0 && e.At < m.Events[i-1].At {
+				t.Errorf("expected events in order, got %v after %v", e.At, m.Events[i-1].At)
+			}
+		}
+
+		want := []gamebot.InputEvent{
+			{Action: gamebot.MoveAction, X: 10, Y: 20},
+			{Action: gamebot.KeyDownAction, Key: "w"},
+			{Action: gamebot.KeyUpAction, Key: "w"},
+			{Action: gamebot.MouseDownAction, Key: "left", X: 30, Y: 40},
+			{Action: gamebot.MouseUpAction, Key: "left", X: 30, Y: 40},
+			{Action: gamebot.ScrollAction, Y: -2},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		if m.Events[0].At != 0 {
+			t.Errorf("expected %v, got %v", time.Duration(0), m.Events[0].At)
+		}
+	})
+
+	t.Run("Test Save and LoadMacro", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "macro.json")
+		if err := m.Save(path); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+
+		loaded, err := gamebot.LoadMacro(path)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if !reflect.DeepEqual(loaded, m) {
+			t.Errorf("expected %v, got %v", m, loaded)
+		}
+	})
+
+	t.Run("Test PlayMacro", func(t *testing.T) {
+		driver.Reset()
+		if err := b.PlayMacro(context.Background(), m, &gamebot.PlayOptions{Speed: 100, Jitter: 0.1}); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+
+		want := []gamebot.InputEvent{
+			{Action: gamebot.MoveAction, X: origin.X + 10, Y: origin.Y + 20},
+			{Action: gamebot.KeyDownAction, Key: "w"},
+			{Action: gamebot.KeyUpAction, Key: "w"},
+			{Action: gamebot.MoveAction, X: origin.X + 30, Y: origin.Y + 40},
+			{Action: gamebot.MouseDownAction, Key: "left"},
+			{Action: gamebot.MouseUpAction, Key: "left"},
+			{Action: gamebot.ScrollAction, Y: -1},
+			{Action: gamebot.ScrollAction, Y: -1},
+		}
+		if got := driver.Events(); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Test PlayMacro releases keys", func(t *testing.T) {
+		held := &gamebot.Macro{Events: []gamebot.MacroEvent{
+			{InputEvent: gamebot.InputEvent{Action: gamebot.KeyDownAction, Key: "w"}},
+		}}
+
+		driver.Reset()
+		if err := b.PlayMacro(context.Background(), held, &gamebot.PlayOptions{Loops: 3}); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+
+		want := []gamebot.InputEvent{
+			{Action: gamebot.KeyDownAction, Key: "w"},
+			{Action: gamebot.KeyUpAction, Key: "w"},
+		}
+		if got := driver.Events(); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		if v := b.KeysDown(); len(v) != 0 {
+			t.Errorf("expected no keys down, got %v", v)
+		}
+	})
+
+	t.Run("Test PlayMacro cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if err := b.PlayMacro(ctx, m, &gamebot.PlayOptions{Loops: -1}); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v, got %v", context.Canceled, err)
+		}
+	})
+
+	t.Run("Test PlayMacro cancelled while typing", func(t *testing.T) {
+		driver.Reset()
+		text := "the quick brown fox jumps over the lazy dog"
+		typing := &gamebot.Macro{Events: []gamebot.MacroEvent{
+			{InputEvent: gamebot.InputEvent{Action: gamebot.TypeAction, Key: text}},
+		}}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		if err := b.PlayMacro(ctx, typing, nil); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+		}
+
+		typed := 0
+		for _, e := range driver.Events() {
+			if e.Action == gamebot.KeyDownAction {
+				typed++
+			}
+		}
+		if typed >= len(text) {
+			t.Errorf("expected typing to stop when the context is cancelled, typed %d keys", typed)
+		}
+	})
+}
+
+func TestRateLimit(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	newBot := func(l gamebot.RateLimit) *gamebot.Bot {
+		b, err := gamebot.NewBot(proc)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		b.SetInputDriver(gamebot.NewFakeDriver())
+		b.SetRateLimit(l)
+		// Taps are instant so the gaps between them are only those of the test.
+		b.SetKeyTiming(gamebot.KeyTiming{})
+		return b
+	}
+
+	t.Run("Test keys per second", func(t *testing.T) {
+		b := newBot(gamebot.RateLimit{KeysPerSecond: 2, Mode: gamebot.RateLimitReject})
+
+		for _, key := range []string{"a", "b"} {
+			if err := b.KeyTap(key); err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+		}
+		if err := b.KeyTap("c"); !errors.Is(err, &gamebot.RateLimitError{}) {
+			t.Errorf("expected %v, got %v", &gamebot.RateLimitError{}, err)
+		}
+		if err := b.MousePress(gamebot.Left); err != nil {
+			t.Errorf("expected clicks not to count towards keys per second, got %v", err)
+		}
+		b.MouseRelease(gamebot.Left)
+
+		stats := b.RateLimitStats()
+		if stats.Allowed != 3 || stats.Rejected != 1 || stats.APM != 3 {
+			t.Errorf("expected 3 allowed, 1 rejected and an APM of 3, got %+v", stats)
+		}
+	})
+
+	t.Run("Test APM", func(t *testing.T) {
+		b := newBot(gamebot.RateLimit{APM: 2, Mode: gamebot.RateLimitReject})
+
+		b.KeyTap("a")
+		b.Click(gamebot.Left, false)
+		if err := b.Scroll(gamebot.WheelDown, 1); !errors.Is(err, &gamebot.RateLimitError{}) {
+			t.Errorf("expected %v, got %v", &gamebot.RateLimitError{}, err)
+		}
+	})
+
+	t.Run("Test MinRepeatGap", func(t *testing.T) {
+		b := newBot(gamebot.RateLimit{MinRepeatGap: 50 * time.Millisecond, Mode: gamebot.RateLimitReject})
+
+		if err := b.KeyTap("a"); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if err := b.KeyTap("b"); err != nil {
+			t.Errorf("expected a different key to be allowed, got %v", err)
+		}
+		if err := b.KeyTap("a"); !errors.Is(err, &gamebot.RateLimitError{}) {
+			t.Errorf("expected %v, got %v", &gamebot.RateLimitError{}, err)
+		}
+
+		time.Sleep(60 * time.Millisecond)
+		if err := b.KeyTap("a"); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+	})
+
+	t.Run("Test blocking", func(t *testing.T) {
+		b := newBot(gamebot.RateLimit{MinRepeatGap: 30 * time.Millisecond})
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			if err := b.MousePress(gamebot.Left); err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+			b.MouseRelease(gamebot.Left)
+		}
+
+		if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+			t.Errorf("expected clicks to be delayed by at least %v, got %v", 60*time.Millisecond, elapsed)
+		}
+		if stats := b.RateLimitStats(); stats.Delayed != 2 || stats.Waited <= 0 {
+			t.Errorf("expected 2 delayed actions, got %+v", stats)
+		}
+	})
+
+	t.Run("Test refused input is not counted", func(t *testing.T) {
+		b := newBot(gamebot.RateLimit{MinRepeatGap: time.Second, Mode: gamebot.RateLimitReject})
+
+		b.Pause()
+		if err := b.KeyTap("a"); !errors.Is(err, gamebot.ErrBotPaused) {
+			t.Errorf("expected %v, got %v", gamebot.ErrBotPaused, err)
+		}
+		if err := b.MousePress(gamebot.Left); !errors.Is(err, gamebot.ErrBotPaused) {
+			t.Errorf("expected %v, got %v", gamebot.ErrBotPaused, err)
+		}
+		if stats := b.RateLimitStats(); stats.Allowed != 0 || stats.APM != 0 {
+			t.Errorf("expected no allowed actions while paused, got %+v", stats)
+		}
+		b.Resume()
+
+		if err := b.PressKey("a"); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if err := b.PressKey("a"); err != nil {
+			t.Errorf("expected pressing a key that is already down not to count towards MinRepeatGap, got %v", err)
+		}
+		b.ReleaseKey("a")
+		b.ReleaseKey("a")
+
+		if stats := b.RateLimitStats(); stats.Allowed != 1 {
+			t.Errorf("expected 1 allowed action, got %+v", stats)
+		}
+	})
+
+	t.Run("Test blocking stops on Close", func(t *testing.T) {
+		b := newBot(gamebot.RateLimit{KeysPerSecond: 1})
+
+		b.KeyTap("a")
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			b.Close()
+		}()
+
+		if err := b.KeyTap("b"); !errors.Is(err, gamebot.ErrBotKilled) {
+			t.Errorf("expected %v, got %v", gamebot.ErrBotKilled, err)
+		}
+	})
+
+	t.Run("Test blocking stops on cancel", func(t *testing.T) {
+		b := newBot(gamebot.RateLimit{KeysPerSecond: 1})
+
+		b.KeyTap("a")
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		if err := b.TypeText(ctx, "b"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("expected the wait to stop when the context is done, waited %v", elapsed)
+		}
+		if keys := b.KeysDown(); len(keys) != 0 {
+			t.Errorf("expected no keys down, got %v", keys)
+		}
+	})
+
+	t.Run("Test debug server", func(t *testing.T) {
+		b := newBot(gamebot.RateLimit{})
+		b.KeyTap("a")
+
+		srv := httptest.NewServer(b.NewDebugServer("").Handler())
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL + "/ratelimit")
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		defer resp.Body.Close()
+
+		var stats gamebot.RateLimitStats
+		if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if stats.Allowed != 1 {
+			t.Errorf("expected %v, got %v", 1, stats.Allowed)
+		}
+	})
+}
+
+func TestExecutor(t *testing.T) {
+	proc := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(proc)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	driver := gamebot.NewFakeDriver()
+	b.SetInputDriver(driver)
+	b.SetDisplayScale(1)
+
+	t.Run("Test priorities", func(t *testing.T) {
+		release := make(chan struct{})
+		first := b.Submit(context.Background(), gamebot.PriorityNormal, func(ctx context.Context) error {
+			<-release
+			return nil
+		})
+
+		var order []string
+		record := func(name string) gamebot.Action {
+			return func(ctx context.Context) error {
+				order = append(order, name)
+				return nil
+			}
+		}
+
+		low := b.Submit(context.Background(), gamebot.PriorityLow, record("low"))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelled := b.Submit(ctx, gamebot.PriorityLow, record("cancelled"))
+		high := b.Submit(context.Background(), gamebot.PriorityHigh, record("high"))
+
+		cancel()
+		if err := cancelled.Wait(context.Background()); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v, got %v", context.Canceled, err)
+		}
+		if err := high.Err(); err != nil {
+			t.Errorf("expected nil error before the action has run, got %v", err)
+		}
+
+		close(release)
+		for _, r := range []*gamebot.ActionResult{first, low, high} {
+			if err := r.Wait(context.Background()); err != nil {
+				t.Errorf("expected nil error, got %v", err)
+			}
+		}
+
+		if want := []string{"high", "low"}; !reflect.DeepEqual(order, want) {
+			t.Errorf("expected %v, got %v", want, order)
+		}
+	})
+
+	t.Run("Test QueueMoveClick", func(t *testing.T) {
+		driver.Reset()
+
+		targets := []image.Point{image.Pt(100, 100), image.Pt(400, 300)}
+		var results []*gamebot.ActionResult
+		for _, p := range targets {
+			results = append(results, b.QueueMoveClick(context.Background(), gamebot.PriorityNormal, p, gamebot.Left))
+		}
+		for _, r := range results {
+			if err := r.Wait(context.Background()); err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+		}
+
+		var clicked []image.Point
+		var cursor image.Point
+		for _, e := range driver.Events() {
+			switch e.Action {
+			case gamebot.MoveAction:
+				cursor = image.Pt(e.X, e.Y)
+			case gamebot.MouseDownAction:
+				clicked = append(clicked, cursor)
+			}
+		}
+		if !reflect.DeepEqual(clicked, targets) {
+			t.Errorf("expected %v, got %v", targets, clicked)
+		}
+	})
+
+	t.Run("Test direct calls wait for the running action", func(t *testing.T) {
+		driver.Reset()
+
+		started, release := make(chan struct{}), make(chan struct{})
+		held := b.Submit(context.Background(), gamebot.PriorityNormal, func(ctx context.Context) error {
+			if err := b.MousePress(gamebot.Left); err != nil {
+				return err
+			}
+			close(started)
+			<-release
+			return b.MouseRelease(gamebot.Left)
+		})
+
+		<-started
+		clicked := make(chan error, 1)
+		go func() {
+			clicked <- b.MoveCursorSmoothClick(300, 200, gamebot.Right, false)
+		}()
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+
+		if err := held.Wait(context.Background()); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if err := <-clicked; err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+
+		down := false
+		for _, e := range driver.Events() {
+			switch {
+			case e.Action == gamebot.MouseDownAction && e.Key == string(gamebot.Left):
+				down = true
+			case e.Action == gamebot.MouseUpAction && e.Key == string(gamebot.Left):
+				down = false
+			case down:
+				t.Errorf("expected no input while the action holds the button, got %+v", e)
+			}
+		}
+	})
+
+	t.Run("Test actions calling high-level methods", func(t *testing.T) {
+		err := b.Run(context.Background(), gamebot.PriorityNormal, func(ctx context.Context) error {
+			if err := b.ClickRect(image.Rect(100, 100, 120, 120), gamebot.Left); err != nil {
+				return err
+			}
+			return b.Drag(ctx, image.Pt(110, 110), image.Pt(200, 110), gamebot.Left, nil)
+		})
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+	})
+
+	t.Run("Test Close", func(t *testing.T) {
+		release := make(chan struct{})
+		b.Submit(context.Background(), gamebot.PriorityNormal, func(ctx context.Context) error {
+			<-release
+			return nil
+		})
+		queued := b.Submit(context.Background(), gamebot.PriorityNormal, func(ctx context.Context) error {
+			t.Errorf("expected queued actions not to run once the bot is closed")
+			return nil
+		})
+
+		b.Close()
+		close(release)
+
+		if err := queued.Wait(context.Background()); !errors.Is(err, gamebot.ErrBotKilled) {
+			t.Errorf("expected %v, got %v", gamebot.ErrBotKilled, err)
+		}
+		if err := b.Run(context.Background(), gamebot.PriorityHigh, func(ctx context.Context) error { return nil }); !errors.Is(err, gamebot.ErrBotKilled) {
+			t.Errorf("expected %v, got %v", gamebot.ErrBotKilled, err)
+		}
+	})
+
+	t.Run("Test Submit while closing", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			b, err := gamebot.NewBot(proc)
+			if err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+			b.SetInputDriver(gamebot.NewFakeDriver())
+
+			var results []*gamebot.ActionResult
+			var mut sync.Mutex
+			var wg sync.WaitGroup
+			for j := 0; j < 4; j++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for k := 0; k < 10; k++ {
+						r := b.Submit(context.Background(), gamebot.PriorityNormal, func(ctx context.Context) error { return nil })
+						mut.Lock()
+						results = append(results, r)
+						mut.Unlock()
+					}
+				}()
+			}
+			b.Close()
+			wg.Wait()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			for _, r := range results {
+				if err := r.Wait(ctx); err != nil && !errors.Is(err, gamebot.ErrBotKilled) {
+					t.Fatalf("expected nil or %v, got %v", gamebot.ErrBotKilled, err)
+				}
+			}
+			cancel()
+		}
+	})
+}
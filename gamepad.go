@@ -0,0 +1,241 @@
+package gamebot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrNoGamepad is returned when the bot is asked to use a gamepad but its input driver does not have one.
+var ErrNoGamepad = errors.New("bot's input driver has no gamepad")
+
+// GamepadButton is the name of a button of a gamepad. The names follow the layout of an Xbox controller.
+type GamepadButton string
+
+const (
+	GamepadA          GamepadButton = "a"
+	GamepadB          GamepadButton = "b"
+	GamepadX          GamepadButton = "x"
+	GamepadY          GamepadButton = "y"
+	GamepadLB         GamepadButton = "lb"
+	GamepadRB         GamepadButton = "rb"
+	GamepadBack       GamepadButton = "back"
+	GamepadStart      GamepadButton = "start"
+	GamepadGuide      GamepadButton = "guide"
+	GamepadLeftThumb  GamepadButton = "leftthumb"
+	GamepadRightThumb GamepadButton = "rightthumb"
+	GamepadDPadUp     GamepadButton = "dpadup"
+	GamepadDPadDown   GamepadButton = "dpaddown"
+	GamepadDPadLeft   GamepadButton = "dpadleft"
+	GamepadDPadRight  GamepadButton = "dpadright"
+)
+
+// GamepadStick is one of the analog sticks of a gamepad.
+type GamepadStick string
+
+const (
+	LeftStick  GamepadStick = "left"
+	RightStick GamepadStick = "right"
+)
+
+// GamepadTrigger is one of the analog triggers of a gamepad.
+type GamepadTrigger string
+
+const (
+	LeftTrigger  GamepadTrigger = "left"
+	RightTrigger GamepadTrigger = "right"
+)
+
+// GamepadDriver is an InputDriver that can also send gamepad input, e.g. a UinputDriver. The bot's gamepad functions
+// return ErrNoGamepad unless its driver is a GamepadDriver that has a gamepad.
+type GamepadDriver interface {
+	InputDriver
+	// HasGamepad returns true if the driver has a gamepad to send input to.
+	HasGamepad() bool
+	// ButtonDown puts `btn` in a down state.
+	ButtonDown(btn GamepadButton) error
+	// ButtonUp puts `btn` in an up state.
+	ButtonUp(btn GamepadButton) error
+	// SetStick tilts `stick` to x, y. Both are between -1 and 1, where -1, -1 is up and to the left.
+	SetStick(stick GamepadStick, x, y float64) error
+	// SetTrigger pulls `trigger` to `value`, between 0 for released and 1 for fully pulled.
+	SetTrigger(trigger GamepadTrigger, value float64) error
+}
+
+// gamepadButtons are the names of every gamepad button.
+var gamepadButtons = map[GamepadButton]bool{
+	GamepadA: true, GamepadB: true, GamepadX: true, GamepadY: true, GamepadLB: true, GamepadRB: true,
+	GamepadBack: true, GamepadStart: true, GamepadGuide: true, GamepadLeftThumb: true, GamepadRightThumb: true,
+	GamepadDPadUp: true, GamepadDPadDown: true, GamepadDPadLeft: true, GamepadDPadRight: true,
+}
+
+// gamepad returns the bot's input driver if it has a gamepad, otherwise ErrNoGamepad. The caller must hold the bot's lock.
+func (b *Bot) gamepad() (GamepadDriver, error) {
+	pad, ok := b.config.input.(GamepadDriver)
+	if !ok || !pad.HasGamepad() {
+		return nil, ErrNoGamepad
+	}
+	return pad, nil
+}
+
+// (b *Bot) PressButton puts a gamepad button in a down state until `(b *Bot) ReleaseButton` is called. Like keys,
+// a button pressed more than once stays down until it has been released as many times. ErrNoGamepad is returned if
+// the bot's input driver has no gamepad, see UinputDriver, and an UnknownKeyError if `btn` is not a gamepad button.
+// The press is subject to the same checks as `(b *Bot) PressKey`.
+func (b *Bot) PressButton(btn GamepadButton) error {
+	if !gamepadButtons[btn] {
+		return NewUnknownKeyError(string(btn))
+	}
+	if err := b.checkInput(); err != nil {
+		return err
+	}
+	if err := b.guardFocus(); err != nil {
+		return err
+	}
+	if !b.isDown("pad" + string(btn)) {
+		if err := b.limitInput(context.Background(), buttonAction, "pad"+string(btn)); err != nil {
+			return err
+		}
+	}
+
+	b.config.botRWMut.Lock()
+	defer b.config.botRWMut.Unlock()
+
+	pad, err := b.gamepad()
+	if err != nil {
+		return err
+	}
+	if err := b.inputErr(); err != nil {
+		return err
+	}
+
+	key := "pad" + string(btn)
+	if b.config.keysDown[key] == 0 {
+		if err := pad.ButtonDown(btn); err != nil {
+			return err
+		}
+	}
+	b.config.keysDown[key]++
+
+	return nil
+}
+
+// (b *Bot) ReleaseButton puts a gamepad button in an up state once it has been released as many times as it was pressed.
+func (b *Bot) ReleaseButton(btn GamepadButton) error {
+	if !gamepadButtons[btn] {
+		return NewUnknownKeyError(string(btn))
+	}
+
+	b.config.botRWMut.Lock()
+	defer b.config.botRWMut.Unlock()
+
+	pad, err := b.gamepad()
+	if err != nil {
+		return err
+	}
+
+	key := "pad" + string(btn)
+	if b.config.keysDown[key] > 1 {
+		b.config.keysDown[key]--
+		return nil
+	}
+
+	delete(b.config.keysDown, key)
+	return pad.ButtonUp(btn)
+}
+
+// (b *Bot) TapButton presses then releases a gamepad button, holding it for the hold time of the bot's KeyTiming.
+func (b *Bot) TapButton(btn GamepadButton) error {
+	if err := b.PressButton(btn); err != nil {
+		return err
+	}
+
+	time.Sleep(b.KeyTiming().Hold.draw(b.rand()))
+
+	return b.ReleaseButton(btn)
+}
+
+// (b *Bot) SetStick tilts a gamepad stick to x, y, where both are between -1 and 1 and -1, -1 is up and to the left.
+// Values outside that range are clamped. The stick stays where it is until it is set again, use 0, 0 to center it.
+// ErrNoGamepad is returned if the bot's input driver has no gamepad.
+func (b *Bot) SetStick(stick GamepadStick, x, y float64) error {
+	if err := b.checkInput(); err != nil {
+		return err
+	}
+	if err := b.guardFocus(); err != nil {
+		return err
+	}
+
+	b.config.botRWMut.RLock()
+	defer b.config.botRWMut.RUnlock()
+
+	pad, err := b.gamepad()
+	if err != nil {
+		return err
+	}
+	if err := b.inputErr(); err != nil {
+		return err
+	}
+
+	return pad.SetStick(stick, clampFloat(x, -1, 1), clampFloat(y, -1, 1))
+}
+
+// (b *Bot) SetTrigger pulls a gamepad trigger to `value`, between 0 for released and 1 for fully pulled. Values
+// outside that range are clamped. ErrNoGamepad is returned if the bot's input driver has no gamepad.
+func (b *Bot) SetTrigger(trigger GamepadTrigger, value float64) error {
+	if err := b.checkInput(); err != nil {
+		return err
+	}
+	if err := b.guardFocus(); err != nil {
+		return err
+	}
+
+	b.config.botRWMut.RLock()
+	defer b.config.botRWMut.RUnlock()
+
+	pad, err := b.gamepad()
+	if err != nil {
+		return err
+	}
+	if err := b.inputErr(); err != nil {
+		return err
+	}
+
+	return pad.SetTrigger(trigger, clampFloat(value, 0, 1))
+}
+
+// resetGamepad centers the sticks and releases the triggers of `pad`, returning the first error.
+func resetGamepad(pad GamepadDriver) error {
+	errs := []error{
+		pad.SetStick(LeftStick, 0, 0),
+		pad.SetStick(RightStick, 0, 0),
+		pad.SetTrigger(LeftTrigger, 0),
+		pad.SetTrigger(RightTrigger, 0),
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gamepadButton returns the gamepad button `key` names in the bot's keys down, or false if it is not a button.
+func gamepadButton(key string) (GamepadButton, bool) {
+	if !strings.HasPrefix(key, "pad") {
+		return "", false
+	}
+	return GamepadButton(strings.TrimPrefix(key, "pad")), true
+}
+
+// clampFloat returns `v` limited to the range min to max.
+func clampFloat(v, min, max float64) float64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
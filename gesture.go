@@ -0,0 +1,103 @@
+package gamebot
+
+import (
+	"context"
+	"image"
+	"time"
+)
+
+const (
+	// dragHoldBeforeMin and dragHoldBeforeMax represent the default range of time a button is held before a drag starts moving.
+	dragHoldBeforeMin = 60 * time.Millisecond
+	dragHoldBeforeMax = 140 * time.Millisecond
+
+	// dragHoldAfterMin and dragHoldAfterMax represent the default range of time a button is held after a drag stops moving.
+	dragHoldAfterMin = 40 * time.Millisecond
+	dragHoldAfterMax = 100 * time.Millisecond
+)
+
+// DragOptions changes how `(b *Bot) Drag` moves. The zero value, or nil, uses the defaults.
+type DragOptions struct {
+	// HoldBefore is how long the button is held down before the cursor starts moving. Some games ignore drags that
+	// start moving as soon as the button is pressed. Defaults to a random duration between 60ms and 140ms.
+	HoldBefore time.Duration
+	// Model generates the path from the start of the drag to the end. Defaults to the bot's motion model.
+	Model MotionModel
+	// HoldAfter is how long the button is held down once the cursor reaches the end of the drag, before it is released.
+	// Defaults to a random duration between 40ms and 100ms.
+	HoldAfter time.Duration
+}
+
+// (b *Bot) Drag moves the cursor to `from`, presses `btn`, moves the cursor to `to` and releases `btn`, e.g. to move an
+// item between inventory slots or to pan a map. `from` and `to` are screen coordinates.
+//
+// If `ctx` is cancelled the drag stops where it is, `btn` is released so it is never left in a down state and the
+// context's error is returned. The drag waits for any queued action that is running, see `(b *Bot) Submit`, and
+// queued actions wait for the drag.
+func (b *Bot) Drag(ctx context.Context, from, to image.Point, btn MouseButton, opts *DragOptions) error {
+	b.config.executor.running.Lock()
+	defer b.config.executor.running.Unlock()
+
+	if opts == nil {
+		opts = &DragOptions{}
+	}
+
+	holdBefore := opts.HoldBefore
+	if holdBefore <= 0 {
+		holdBefore = b.randomDuration(dragHoldBeforeMin, dragHoldBeforeMax)
+	}
+	holdAfter := opts.HoldAfter
+	if holdAfter <= 0 {
+		holdAfter = b.randomDuration(dragHoldAfterMin, dragHoldAfterMax)
+	}
+	model := opts.Model
+	if model == nil {
+		model = b.MotionModel()
+	}
+
+	mx, my := b.MousePosition()
+	if err := b.followPath(ctx, b.CursorPath(image.Pt(mx, my), from)); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := b.mousePress(ctx, btn); err != nil {
+		return err
+	}
+	defer b.MouseRelease(btn)
+
+	if err := sleepContext(ctx, holdBefore); err != nil {
+		return err
+	}
+	if err := b.followPath(ctx, model.Path(from, to, b.rand())); err != nil {
+		return err
+	}
+
+	return sleepContext(ctx, holdAfter)
+}
+
+// (b *Bot) LongPress moves the cursor to `p` and holds `btn` down for `d`, e.g. to open a context menu in a game
+// designed for touch screens. `p` is in screen coordinates.
+//
+// If `ctx` is cancelled `btn` is released straight away and the context's error is returned.
+func (b *Bot) LongPress(ctx context.Context, p image.Point, btn MouseButton, d time.Duration) error {
+	b.config.executor.running.Lock()
+	defer b.config.executor.running.Unlock()
+
+	mx, my := b.MousePosition()
+	if err := b.followPath(ctx, b.CursorPath(image.Pt(mx, my), p)); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := b.mousePress(ctx, btn); err != nil {
+		return err
+	}
+	defer b.MouseRelease(btn)
+
+	return sleepContext(ctx, d)
+}
@@ -0,0 +1,115 @@
+package gamebot
+
+import (
+	"sync"
+	"time"
+)
+
+// KeyHold is a key, or chord of keys, held down by `(b *Bot) HoldKey`. The keys are released automatically when the
+// hold expires. A hold can be extended while it is running or released early.
+//
+// Holds of the same key may overlap, e.g. a movement loop and a pathing loop both holding "w". The key stays down until
+// every hold of it has been released. Tapping a key that is held, e.g. with `(b *Bot) KeyTap`, is absorbed by the hold:
+// the key is neither released nor pressed again, so the game does not see the tap.
+type KeyHold struct {
+	bot  *Bot
+	keys []Key
+
+	mut      sync.Mutex
+	timer    *time.Timer
+	deadline time.Time
+	released bool
+	done     chan struct{}
+}
+
+// (b *Bot) HoldKey presses `key`, or a chord of keys joined with a `+` such as "shift+w", and returns immediately.
+// The keys are released after `d` unless the returned hold is extended or released first.
+//
+// An UnknownKeyError is returned, and nothing is pressed, if any key of the chord is not supported.
+// Reference https://github.com/go-vgo/robotgo/blob/master/docs/keys.md for full list of keycodes.
+func (b *Bot) HoldKey(key string, d time.Duration) (*KeyHold, error) {
+	keys, err := parseChord(key)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &KeyHold{
+		bot:  b,
+		keys: keys,
+		done: make(chan struct{}),
+	}
+
+	for i, k := range h.keys {
+		if err := b.PressKey(k); err != nil {
+			b.releaseKeys(h.keys[:i])
+			return nil, err
+		}
+	}
+
+	// The hold is registered before its timer is armed, so a timer that fires straight away always finds it to
+	// remove.
+	b.config.botRWMut.Lock()
+	b.config.holds[h] = struct{}{}
+	b.config.botRWMut.Unlock()
+
+	// The deadline is taken once the keys are down, as pressing them may have waited on the rate limit or focus guard.
+	h.mut.Lock()
+	if !h.released {
+		h.deadline = time.Now().Add(d)
+		h.timer = time.AfterFunc(d, h.Release)
+	}
+	h.mut.Unlock()
+
+	return h, nil
+}
+
+// (h *KeyHold) Extend keeps the keys held for `d` longer than they would otherwise have been.
+// It returns false if the hold has already been released.
+func (h *KeyHold) Extend(d time.Duration) bool {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	if h.released || !h.timer.Stop() {
+		return false
+	}
+
+	h.deadline = h.deadline.Add(d)
+	h.timer.Reset(time.Until(h.deadline))
+
+	return true
+}
+
+// (h *KeyHold) Release releases the keys straight away. Releasing a hold more than once has no effect.
+func (h *KeyHold) Release() {
+	h.mut.Lock()
+	if h.released {
+		h.mut.Unlock()
+		return
+	}
+	h.released = true
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+	h.mut.Unlock()
+
+	h.bot.releaseKeys(h.keys)
+
+	h.bot.config.botRWMut.Lock()
+	delete(h.bot.config.holds, h)
+	h.bot.config.botRWMut.Unlock()
+
+	close(h.done)
+}
+
+// (h *KeyHold) Deadline returns the time the keys will be released if the hold is not extended or released first.
+func (h *KeyHold) Deadline() time.Time {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	return h.deadline
+}
+
+// (h *KeyHold) Done returns a channel that is closed once the keys have been released.
+func (h *KeyHold) Done() <-chan struct{} {
+	return h.done
+}
@@ -0,0 +1,178 @@
+package gamebot
+
+import (
+	"sync"
+	"time"
+
+	hook "github.com/robotn/gohook"
+)
+
+// HookEvent is keyboard or mouse input made anywhere on the system, e.g. by the player, delivered by an EventSource.
+// Mouse positions are in the operating system's units, see CoordinateTransform.
+type HookEvent struct {
+	InputEvent
+	// When is the time the input was made.
+	When time.Time
+}
+
+// EventSource delivers the keyboard and mouse input made anywhere on the system. The bot listens to its source for
+// hotkeys, see `(b *Bot) RegisterHotkey`, and to record macros.
+type EventSource interface {
+	// Listen delivers every event to the returned channel until `stop` is called, which closes the channel.
+	// A source must support several listeners at once.
+	Listen() (events <-chan HookEvent, stop func(), err error)
+}
+
+// (b *Bot) SetEventSource sets the source the bot listens to for hotkeys and macros, e.g. a FakeEventSource in tests.
+// If `src` is nil the default source, which uses gohook, is used.
+func (b *Bot) SetEventSource(src EventSource) {
+	b.config.botRWMut.Lock()
+	defer b.config.botRWMut.Unlock()
+
+	if src == nil {
+		src = gohookSource{}
+	}
+	b.config.events = src
+}
+
+// eventSource returns the source the bot listens to for hotkeys and macros.
+func (b *Bot) eventSource() EventSource {
+	b.config.botRWMut.RLock()
+	defer b.config.botRWMut.RUnlock()
+
+	return b.config.events
+}
+
+// hookListenerBuffer is the number of events buffered for each listener of the gohook source.
+const hookListenerBuffer = 256
+
+// gohookSource delivers the input seen by gohook's global hook. The hook is started when the first listener
+// starts listening and stopped when the last one stops, as gohook only supports a single hook per process.
+type gohookSource struct{}
+
+var hookHub struct {
+	mut       sync.Mutex
+	listeners map[chan HookEvent]struct{}
+}
+
+func (gohookSource) Listen() (<-chan HookEvent, func(), error) {
+	hookHub.mut.Lock()
+	defer hookHub.mut.Unlock()
+
+	if len(hookHub.listeners) == 0 {
+		hookHub.listeners = make(map[chan HookEvent]struct{})
+		go forwardHookEvents(hook.Start())
+	}
+
+	ch := make(chan HookEvent, hookListenerBuffer)
+	hookHub.listeners[ch] = struct{}{}
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			hookHub.mut.Lock()
+			defer hookHub.mut.Unlock()
+
+			delete(hookHub.listeners, ch)
+			close(ch)
+			if len(hookHub.listeners) == 0 {
+				hook.End()
+			}
+		})
+	}
+
+	return ch, stop, nil
+}
+
+// forwardHookEvents converts the events gohook delivers on `events` and sends them to every listener until the
+// hook is stopped. Listeners that fall behind miss events rather than stalling the hook.
+func forwardHookEvents(events chan hook.Event) {
+	for ev := range events {
+		e, ok := convertHookEvent(ev)
+		if !ok {
+			continue
+		}
+
+		hookHub.mut.Lock()
+		for ch := range hookHub.listeners {
+			select {
+			case ch <- e:
+			default:
+			}
+		}
+		hookHub.mut.Unlock()
+	}
+}
+
+// hookMouseButtons maps gohook's mouse buttons to the bot's.
+var hookMouseButtons = map[uint16]MouseButton{
+	1: "left",
+	2: "right",
+	3: "center",
+}
+
+// hookKeycodes maps the virtual keycodes gohook reports to the bot's key names.
+var hookKeycodes = map[uint16]Key{
+	0x0001: KeyEscape, 0x000E: KeyBackspace, 0x000F: KeyTab, 0x001C: KeyEnter, 0x0039: KeySpace,
+	0x003A: KeyCapsLock, 0x0045: KeyNumLock, 0x0E37: KeyPrintScreen, 0x0E5D: KeyMenu,
+	0x0E52: KeyInsert, 0x0E53: KeyDelete, 0x0E47: KeyHome, 0x0E4F: KeyEnd, 0x0E49: KeyPageUp, 0x0E51: KeyPageDown,
+	0xE048: KeyUp, 0xE050: KeyDown, 0xE04B: KeyLeft, 0xE04D: KeyRight,
+	0x002A: KeyShift, 0x0036: KeyRShift, 0x001D: KeyCtrl, 0x0E1D: KeyRCtrl,
+	0x0038: KeyAlt, 0x0E38: KeyRAlt, 0x0E5B: KeyCmd, 0x0E5C: KeyRCmd,
+	0x003B: KeyF1, 0x003C: KeyF2, 0x003D: KeyF3, 0x003E: KeyF4, 0x003F: KeyF5, 0x0040: KeyF6,
+	0x0041: KeyF7, 0x0042: KeyF8, 0x0043: KeyF9, 0x0044: KeyF10, 0x0057: KeyF11, 0x0058: KeyF12,
+	0x0029: "`", 0x0002: "1", 0x0003: "2", 0x0004: "3", 0x0005: "4", 0x0006: "5", 0x0007: "6", 0x0008: "7",
+	0x0009: "8", 0x000A: "9", 0x000B: "0", 0x000C: "-", 0x000D: "=",
+	0x0010: "q", 0x0011: "w", 0x0012: "e", 0x0013: "r", 0x0014: "t", 0x0015: "y", 0x0016: "u", 0x0017: "i",
+	0x0018: "o", 0x0019: "p", 0x001A: "[", 0x001B: "]", 0x002B: "\\",
+	0x001E: "a", 0x001F: "s", 0x0020: "d", 0x0021: "f", 0x0022: "g", 0x0023: "h", 0x0024: "j", 0x0025: "k",
+	0x0026: "l", 0x0027: ";", 0x0028: "'",
+	0x002C: "z", 0x002D: "x", 0x002E: "c", 0x002F: "v", 0x0030: "b", 0x0031: "n", 0x0032: "m",
+	0x0033: ",", 0x0034: ".", 0x0035: "/",
+}
+
+// hookWheelHorizontal is the direction gohook reports for a horizontal scroll.
+const hookWheelHorizontal = 4
+
+// convertHookEvent converts an event delivered by gohook, or returns false if the event is not one the bot uses.
+func convertHookEvent(ev hook.Event) (HookEvent, bool) {
+	e := HookEvent{When: ev.When}
+
+	// gohook's event kinds follow libuiohook, where KeyHold is a key being pressed and MouseHold and MouseDown are a
+	// button being pressed and released.
+	switch ev.Kind {
+	case hook.KeyHold, hook.KeyUp:
+		key, ok := hookKeycodes[ev.Keycode]
+		if !ok {
+			return HookEvent{}, false
+		}
+		e.Action, e.Key = KeyDownAction, string(key)
+		if ev.Kind == hook.KeyUp {
+			e.Action = KeyUpAction
+		}
+	case hook.MouseHold, hook.MouseDown:
+		btn, ok := hookMouseButtons[ev.Button]
+		if !ok {
+			return HookEvent{}, false
+		}
+		e.Action, e.Key = MouseDownAction, string(btn)
+		if ev.Kind == hook.MouseDown {
+			e.Action = MouseUpAction
+		}
+		e.X, e.Y = int(ev.X), int(ev.Y)
+	case hook.MouseMove, hook.MouseDrag:
+		e.Action, e.X, e.Y = MoveAction, int(ev.X), int(ev.Y)
+	case hook.MouseWheel:
+		// gohook reports a negative rotation for scrolling up, the bot scrolls up for a positive y.
+		e.Action = ScrollAction
+		if ev.Direction == hookWheelHorizontal {
+			e.X = -int(ev.Rotation)
+		} else {
+			e.Y = -int(ev.Rotation)
+		}
+	default:
+		return HookEvent{}, false
+	}
+
+	return e, true
+}
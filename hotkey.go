@@ -0,0 +1,320 @@
+package gamebot
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrBotPaused is returned when the bot is asked to send input while it is paused.
+	ErrBotPaused = errors.New("bot is paused")
+	// ErrBotKilled is returned when the bot is asked to send input after it has been killed.
+	ErrBotKilled = errors.New("bot has been killed")
+)
+
+// hotkey is a chord registered with RegisterHotkey and the function called when it is pressed.
+type hotkey struct {
+	keys    []Key
+	handler func()
+}
+
+// ControlHotkeys are the chords that control a running bot, see `(b *Bot) RegisterControlHotkeys`.
+// A chord that is empty is not registered.
+type ControlHotkeys struct {
+	// Pause pauses the bot, or resumes it if it is already paused.
+	Pause string
+	// Step lets a paused bot run until it next calls `(b *Bot) WaitWhilePaused`.
+	Step string
+	// Kill releases every key and stops the bot for good, see `(b *Bot) Kill`.
+	Kill string
+}
+
+// DefaultControlHotkeys returns F9 to pause and resume, F10 to step and Ctrl+F12 to kill the bot.
+func DefaultControlHotkeys() ControlHotkeys {
+	return ControlHotkeys{
+		Pause: "f9",
+		Step:  "f10",
+		Kill:  "ctrl+f12",
+	}
+}
+
+// (b *Bot) RegisterHotkey calls `handler` whenever `chord`, e.g. "ctrl+shift+p", is pressed anywhere on the system, even
+// while the game has focus. The chord is pressed when its last key goes down while the others are held. Modifiers
+// match either side of the keyboard, e.g. "ctrl" matches both control keys. Characters typed with shift on a US
+// keyboard are the shifted key, e.g. "ctrl+P" is the same chord as "ctrl+shift+p" and "ctrl+!" as "ctrl+shift+1".
+//
+// Hotkeys are only seen while `(b *Bot) ListenHotkeys` is running. Handlers are called one at a time by the listener,
+// so a handler that takes a while should start a goroutine. An UnknownKeyError is returned if a key of the chord is
+// not supported.
+func (b *Bot) RegisterHotkey(chord string, handler func()) error {
+	keys, err := parseChord(chord)
+	if err != nil {
+		return err
+	}
+	keys = hotkeyKeys(keys)
+
+	b.config.botRWMut.Lock()
+	defer b.config.botRWMut.Unlock()
+
+	b.config.hotkeys = append(b.config.hotkeys, hotkey{keys: keys, handler: handler})
+	return nil
+}
+
+// (b *Bot) RegisterControlHotkeys registers hotkeys that pause, resume, step and kill the bot, so a bot can be stopped
+// without switching to its terminal. See DefaultControlHotkeys.
+func (b *Bot) RegisterControlHotkeys(c ControlHotkeys) error {
+	controls := []struct {
+		chord   string
+		handler func()
+	}{
+		{c.Pause, b.TogglePause},
+		{c.Step, b.Step},
+		{c.Kill, func() { b.Kill() }},
+	}
+
+	for _, ctl := range controls {
+		if ctl.chord == "" {
+			continue
+		}
+		if err := b.RegisterHotkey(ctl.chord, ctl.handler); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// (b *Bot) ListenHotkeys listens to the bot's event source and calls the handlers of the registered hotkeys, see
+// `(b *Bot) SetEventSource`. It blocks until `ctx` is cancelled or the bot is closed, then returns nil.
+func (b *Bot) ListenHotkeys(ctx context.Context) error {
+	events, stop, err := b.eventSource().Listen()
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	down := make(map[Key]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-b.config.done:
+			return nil
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			b.handleHotkeyEvent(down, e.InputEvent)
+		}
+	}
+}
+
+// handleHotkeyEvent updates the keys that are `down` and calls the handler of every hotkey pressed by `e`.
+func (b *Bot) handleHotkeyEvent(down map[Key]bool, e InputEvent) {
+	var key Key
+	switch e.Action {
+	case KeyDownAction, KeyUpAction:
+		key = sideless(Key(e.Key))
+	case MouseDownAction, MouseUpAction:
+		key = Key("mouse" + e.Key)
+	default:
+		return
+	}
+
+	if e.Action == KeyUpAction || e.Action == MouseUpAction {
+		delete(down, key)
+		return
+	}
+
+	// Ignore the repeated events sent while a key is held.
+	if down[key] {
+		return
+	}
+	down[key] = true
+
+	b.config.botRWMut.RLock()
+	hotkeys := append([]hotkey{}, b.config.hotkeys...)
+	b.config.botRWMut.RUnlock()
+
+	for _, h := range hotkeys {
+		if h.keys[len(h.keys)-1] != key {
+			continue
+		}
+
+		pressed := true
+		for _, k := range h.keys {
+			pressed = pressed && down[k]
+		}
+		if pressed {
+			h.handler()
+		}
+	}
+}
+
+// hotkeyKeys returns the keys of a chord as the event source reports them: modifiers without a side, and characters
+// typed with shift as KeyShift and the unshifted key, e.g. "P" as KeyShift and "p". Keys that appear twice are kept once.
+func hotkeyKeys(keys []Key) []Key {
+	var out []Key
+	add := func(k Key) {
+		for _, o := range out {
+			if o == k {
+				return
+			}
+		}
+		out = append(out, k)
+	}
+
+	for _, k := range keys {
+		if r := []rune(string(k)); len(r) == 1 {
+			if shifted, ok := charKeys(r[0]); ok && len(shifted) > 1 {
+				for _, s := range shifted {
+					add(s)
+				}
+				continue
+			}
+		}
+		add(sideless(k))
+	}
+
+	return out
+}
+
+// sideless returns the modifier `k` is a side of, e.g. KeyCtrl for KeyRCtrl, or `k` if it is not a modifier.
+func sideless(k Key) Key {
+	switch k {
+	case KeyLShift, KeyRShift:
+		return KeyShift
+	case KeyLCtrl, KeyRCtrl:
+		return KeyCtrl
+	case KeyLAlt, KeyRAlt:
+		return KeyAlt
+	case KeyLCmd, KeyRCmd:
+		return KeyCmd
+	}
+	return k
+}
+
+// (b *Bot) Pause stops the bot from sending input until it is resumed. Every key and mouse button the bot is holding is
+// released, so the character does not keep running while the bot is paused. Input sent while the bot is paused is
+// refused with ErrBotPaused. Bots should call `(b *Bot) WaitWhilePaused` at the top of their loop.
+func (b *Bot) Pause() {
+	b.setPaused(true)
+	b.ReleaseAll()
+}
+
+// (b *Bot) Resume lets a paused bot send input again.
+func (b *Bot) Resume() {
+	b.setPaused(false)
+}
+
+// (b *Bot) TogglePause pauses the bot if it is running and resumes it if it is paused.
+func (b *Bot) TogglePause() {
+	if b.Paused() {
+		b.Resume()
+	} else {
+		b.Pause()
+	}
+}
+
+// (b *Bot) Paused returns true if the bot is paused.
+func (b *Bot) Paused() bool {
+	b.config.botRWMut.RLock()
+	defer b.config.botRWMut.RUnlock()
+
+	return b.config.paused
+}
+
+// (b *Bot) Step lets a paused bot run until it next calls `(b *Bot) WaitWhilePaused`, e.g. to watch a single
+// iteration of its loop. Step does nothing if the bot is not paused.
+func (b *Bot) Step() {
+	b.config.botRWMut.Lock()
+	defer b.config.botRWMut.Unlock()
+
+	if !b.config.paused {
+		return
+	}
+	b.config.stepRequested = true
+	b.notifyPauseChanged()
+}
+
+// (b *Bot) Kill releases every key and mouse button and closes the bot, see `(b *Bot) Close`. A killed bot refuses
+// all further input with ErrBotKilled and cannot be resumed.
+func (b *Bot) Kill() error {
+	b.config.botRWMut.Lock()
+	b.config.killed = true
+	b.notifyPauseChanged()
+	b.config.botRWMut.Unlock()
+
+	return b.Close()
+}
+
+// (b *Bot) WaitWhilePaused blocks while the bot is paused. It returns nil once the bot is resumed or stepped, see
+// `(b *Bot) Step`, ErrBotKilled if the bot is killed, or the context's error if `ctx` is cancelled.
+func (b *Bot) WaitWhilePaused(ctx context.Context) error {
+	for {
+		b.config.botRWMut.Lock()
+
+		// A step only lasts until the bot next waits.
+		b.config.stepping = false
+
+		switch {
+		case b.config.killed:
+			b.config.botRWMut.Unlock()
+			return ErrBotKilled
+		case !b.config.paused:
+			b.config.botRWMut.Unlock()
+			return nil
+		case b.config.stepRequested:
+			b.config.stepRequested = false
+			b.config.stepping = true
+			b.config.botRWMut.Unlock()
+			return nil
+		}
+
+		changed := b.config.pauseChanged
+		b.config.botRWMut.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// setPaused pauses or resumes the bot.
+func (b *Bot) setPaused(paused bool) {
+	b.config.botRWMut.Lock()
+	defer b.config.botRWMut.Unlock()
+
+	b.config.paused = paused
+	b.config.stepRequested = false
+	b.config.stepping = false
+	b.notifyPauseChanged()
+}
+
+// notifyPauseChanged wakes up every goroutine in WaitWhilePaused. The caller must hold the bot's lock.
+func (b *Bot) notifyPauseChanged() {
+	close(b.config.pauseChanged)
+	b.config.pauseChanged = make(chan struct{})
+}
+
+// inputErr returns the reason the bot may not send input, or nil if it may. The caller must hold the bot's lock.
+// Releasing keys is always allowed so a paused or killed bot never leaves keys pressed.
+func (b *Bot) inputErr() error {
+	switch {
+	case b.config.killed:
+		return ErrBotKilled
+	case b.config.paused && !b.config.stepping:
+		return ErrBotPaused
+	}
+	return nil
+}
+
+// checkInput returns the reason the bot may not send input, or nil if it may.
+func (b *Bot) checkInput() error {
+	b.config.botRWMut.RLock()
+	defer b.config.botRWMut.RUnlock()
+
+	return b.inputErr()
+}
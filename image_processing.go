@@ -1,10 +1,15 @@
 package gamebot
 
 import (
+	"context"
 	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
 	"os"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/go-vgo/robotgo"
 	"gocv.io/x/gocv"
@@ -38,31 +43,252 @@ func (b *Bot) CaptureWindow() *image.Image {
 // This function returns the minValue, maxValue, minLocation and maxLocation of the matched image.
 // If an error occurs and error is returned.
 //
-// The `in` parameter represents the larger image where `tmpl` is the template image to search for.
+// The `in` parameter represents the larger image where `tmpl` is the template to search for.
+// If the template has a region only that area of `in` is searched.
 //
 // This function utilized opencv's TmCcoeffNormed algorithm by default. To change the algorithm use
 // the `(b *Bot) SetCVMatchMode()`.
-func (b *Bot) DetectImage(in *image.Image, tmpl *image.Image) (float32, float32, *image.Point, *image.Point, error) {
+func (b *Bot) DetectImage(in *image.Image, tmpl *Template) (float32, float32, *image.Point, *image.Point, error) {
+	m, err := b.Detect(in, tmpl)
+	if err != nil {
+		return 0, 0, nil, nil, err
+	}
+
+	return m.MinValue, m.MaxValue, &m.MinLocation, &m.MaxLocation, nil
+}
+
+// (b *Bot) Detect searches `in` for `tmpl` and returns the best match.
+// If the template has a region only that area of `in` is searched.
+func (b *Bot) Detect(in *image.Image, tmpl *Template) (Match, error) {
+	return b.detectIn(in, tmpl, tmpl.Region)
+}
+
+// detectIn searches the `region` area of `in` for `tmpl`. If `region` is empty the whole image is searched.
+func (b *Bot) detectIn(in *image.Image, tmpl *Template, region image.Rectangle) (Match, error) {
 	inMat, err := gocv.ImageToMatRGB(*in)
 	if err != nil {
-		return 0, 0, nil, nil, fmt.Errorf("failed to convert img to gocv.Mat: %v", err)
+		return Match{}, fmt.Errorf("failed to convert img to gocv.Mat: %v", err)
 	}
-	tmplMat, err := gocv.ImageToMatRGB(*tmpl)
+	defer inMat.Close()
+
+	b.config.botRWMut.RLock()
+	matchMode := b.config.cvMatchMode
+	b.config.botRWMut.RUnlock()
+
+	return matchTemplate(inMat, tmpl, region, matchMode)
+}
+
+// Match represents the result of searching for a template within a larger image.
+type Match struct {
+	// Name is the name of the template that produced the match.
+	Name string
+
+	MinValue    float32
+	MaxValue    float32
+	MinLocation image.Point
+	MaxLocation image.Point
+
+	// Score is the value of the best match for the match mode that was used. For the Sqdiff
+	// modes this is the minimum value, for every other mode it is the maximum value.
+	Score float32
+	// Location is the top-left corner of the best match for the match mode that was used.
+	Location image.Point
+	// Size is the width and height of the template.
+	Size image.Point
+	// Found is true if the score passes the template's threshold.
+	Found bool
+}
+
+// (m Match) Rect returns the area of the window covered by the best match.
+func (m Match) Rect() image.Rectangle {
+	return image.Rectangle{Min: m.Location, Max: m.Location.Add(m.Size)}
+}
+
+// (b *Bot) DetectMany scans `in` for every template in `tmpls` and returns a map of template name to Match.
+//
+// The `in` image is converted once and the templates are matched concurrently by a bounded pool of workers.
+// The number of workers defaults to the number of CPUs, use `(b *Bot) SetDetectWorkers()` to change it.
+// The returned matches do not depend on the order the workers finish in. If `ctx` is cancelled before every
+// template has been matched the context's error is returned. Every template must have a unique name and none may be nil.
+func (b *Bot) DetectMany(ctx context.Context, in *image.Image, tmpls []*Template) (map[string]Match, error) {
+	for i, tmpl := range tmpls {
+		if tmpl == nil {
+			return nil, fmt.Errorf("template %d is nil", i)
+		}
+	}
+
+	// Work through the templates in a fixed order so the result, including which error is reported
+	// when several templates fail, is the same every time.
+	sorted := make([]*Template, len(tmpls))
+	copy(sorted, tmpls)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Name == sorted[i-1].Name {
+			return nil, fmt.Errorf("more then one template is named %s", sorted[i].Name)
+		}
+	}
+
+	matches, err := b.detectTemplates(ctx, in, sorted)
 	if err != nil {
-		return 0, 0, nil, nil, fmt.Errorf("failed to convert img to gocv.Mat: %v", err)
+		return nil, err
+	}
+
+	result := make(map[string]Match, len(sorted))
+	for i, tmpl := range sorted {
+		result[tmpl.Name] = matches[i]
+	}
+
+	return result, nil
+}
+
+// detectTemplates matches each of `tmpls` against `in` concurrently, see `(b *Bot) DetectMany`, and returns the
+// matches in the same order as `tmpls`. Templates are not required to have unique names. The first error in the
+// order of `tmpls` is returned.
+func (b *Bot) detectTemplates(ctx context.Context, in *image.Image, tmpls []*Template) ([]Match, error) {
+	inMat, err := gocv.ImageToMatRGB(*in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert img to gocv.Mat: %v", err)
+	}
+	defer inMat.Close()
+
+	b.config.botRWMut.RLock()
+	matchMode := b.config.cvMatchMode
+	workers := b.config.detectWorkers
+	b.config.botRWMut.RUnlock()
+
+	if workers > len(tmpls) {
+		workers = len(tmpls)
+	}
+
+	matches := make([]Match, len(tmpls))
+	errs := make([]error, len(tmpls))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				matches[i], errs[i] = matchTemplate(inMat, tmpls[i], tmpls[i].Region, matchMode)
+			}
+		}()
+	}
+
+feed:
+	for i := range tmpls {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, tmpl := range tmpls {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("failed to detect %s: %v", tmpl.Name, errs[i])
+		}
+	}
+
+	return matches, nil
+}
+
+// (b *Bot) DetectWorkers returns the maximum number of templates `(b *Bot) DetectMany` will match at the same time.
+func (b *Bot) DetectWorkers() int {
+	b.config.botRWMut.RLock()
+	defer b.config.botRWMut.RUnlock()
+
+	return b.config.detectWorkers
+}
+
+// (b *Bot) SetDetectWorkers sets the maximum number of templates `(b *Bot) DetectMany` will match at the same time.
+// Values less than 1 are treated as 1.
+func (b *Bot) SetDetectWorkers(n int) {
+	b.config.botRWMut.Lock()
+	defer b.config.botRWMut.Unlock()
+
+	if n < 1 {
+		n = 1
+	}
+	b.config.detectWorkers = n
+}
+
+// matchTemplate searches the `region` area of `inMat` for `tmpl` using the provided match mode. If `region` is
+// empty, or too small to hold the template, the whole of `inMat` is searched. The `inMat` is only read from so
+// it is safe to share between concurrent calls.
+func matchTemplate(inMat gocv.Mat, tmpl *Template, region image.Rectangle, matchMode gocv.TemplateMatchMode) (Match, error) {
+	tmpl.mut.RLock()
+	defer tmpl.mut.RUnlock()
+
+	if tmpl.closed {
+		return Match{}, fmt.Errorf("template %s is closed", tmpl.Name)
 	}
 
-	result, mask := gocv.NewMat(), gocv.NewMat()
+	size := tmpl.Size()
+	bounds := image.Rect(0, 0, inMat.Cols(), inMat.Rows())
+
+	search, offset := inMat, image.Point{}
+	if region = region.Intersect(bounds); region.Dx() >= size.X && region.Dy() >= size.Y {
+		search, offset = inMat.Region(region), region.Min
+		defer search.Close()
+	}
+
+	if search.Cols() < size.X || search.Rows() < size.Y {
+		return Match{}, fmt.Errorf("template %s of size %v is larger then the image %v", tmpl.Name, size, bounds.Size())
+	}
 
-	gocv.MatchTemplate(inMat, tmplMat, &result, b.config.cvMatchMode, mask)
+	result := gocv.NewMat()
+	defer result.Close()
 
-	inMat.Close()
-	tmplMat.Close()
-	mask.Close()
+	gocv.MatchTemplate(search, tmpl.mat, &result, matchMode, tmpl.mask)
 	mnv, mxv, mnl, mxl := gocv.MinMaxLoc(result)
 
-	result.Close()
-	return mnv, mxv, &mnl, &mxl, nil
+	m := Match{
+		Name:        tmpl.Name,
+		MinValue:    mnv,
+		MaxValue:    mxv,
+		MinLocation: mnl.Add(offset),
+		MaxLocation: mxl.Add(offset),
+		Size:        size,
+	}
+
+	m.Score, m.Location = m.MaxValue, m.MaxLocation
+	if isSqdiff(matchMode) {
+		m.Score, m.Location = m.MinValue, m.MinLocation
+	}
+	m.Found = matchPasses(matchMode, m.Score, tmpl.Threshold)
+
+	return m, nil
+}
+
+// isSqdiff returns true if the best match for the match mode is the minimum value rather than the maximum.
+func isSqdiff(matchMode gocv.TemplateMatchMode) bool {
+	return matchMode == gocv.TmSqdiff || matchMode == gocv.TmSqdiffNormed
+}
+
+// matchPasses returns true if `score` is at least as good as `threshold` for the match mode.
+func matchPasses(matchMode gocv.TemplateMatchMode, score, threshold float32) bool {
+	if isSqdiff(matchMode) {
+		return score <= threshold
+	}
+	return score >= threshold
+}
+
+// cropImage copies the area `rect` of `img` into a new image whose top-left corner is 0, 0.
+func cropImage(img image.Image, rect image.Rectangle) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, rect.Min, draw.Src)
+	return dst
 }
 
 // (b *Bot) SetCVMatchMode returns the opencv template matching mode.
@@ -83,34 +309,124 @@ func (b *Bot) SetCVMatchMode(matchMode gocv.TemplateMatchMode) {
 	b.config.cvMatchMode = matchMode
 }
 
-// (b *Bot) ShowDetectedImage this function opens a window creating a rectangle around the min and max areas of the detected image.
-// Pressing the 'q' key will close this window. This function is mostly used for debugging your bot.
+var (
+	// debugPassColor and debugFailColor are the colors ShowDetectedImage draws matches that do and do not pass their threshold.
+	debugPassColor = color.RGBA{0, 255, 0, 255}
+	debugFailColor = color.RGBA{255, 0, 0, 255}
+)
+
+const (
+	// debugKeyQuit, debugKeyEscape, debugKeyPause and debugKeyStep are the keys ShowDetectedImage responds to.
+	debugKeyQuit   = 'q'
+	debugKeyEscape = 27
+	debugKeyPause  = ' '
+	debugKeyStep   = 'n'
+
+	// debugWaitMs represents how long ShowDetectedImage waits for a key press between frames, in milliseconds.
+	debugWaitMs = 10
+)
+
+// (b *Bot) ShowDetectedImage opens a window showing the bot's window with the best match of each template drawn on top of it.
+// This function is mostly used for debugging your bot.
+//
+// Each match is labelled with the template's name and score. Matches that pass the template's threshold are drawn in green,
+// matches that fail are drawn in red. The frames per second are shown in the top-left corner.
 //
-// The `tmpl` is the template image to search for within the game window.
-// This function will print the minValue, maxValue, MinLocation, and MaxLocation.
-func (b *Bot) ShowDetectedImage(windowTitle string, tmpl *image.Image) error {
+// While the window has focus the following keys are available:
+//
+//	space  pause or resume capturing
+//	n      capture a single frame while paused
+//	q, esc close the window
+//
+// The window is closed and nil is returned when 'q' is pressed or `ctx` is cancelled. This function must be called from the
+// goroutine that will handle the window's events, usually the main goroutine.
+func (b *Bot) ShowDetectedImage(ctx context.Context, windowTitle string, tmpls ...*Template) error {
 	w := gocv.NewWindow(windowTitle)
-	imgX, imgY := (*tmpl).Bounds().Dx(), (*tmpl).Bounds().Dy()
+	defer w.Close()
+
+	frame := gocv.NewMat()
+	defer func() { frame.Close() }()
+
+	var (
+		paused    bool
+		step      bool
+		fps       float64
+		lastFrame = time.Now()
+	)
 
 	for {
-		src := b.CaptureWindow()
-		mnv, mxv, mnl, mxl, err := b.DetectImage(src, tmpl)
-		if err != nil {
-			return err
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if !paused || step {
+			step = false
+
+			next, err := b.detectedFrame(ctx, tmpls)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+
+			now := time.Now()
+			if elapsed := now.Sub(lastFrame).Seconds(); elapsed > 0 {
+				// Smooth the frame rate so the overlay is readable.
+				fps = 0.9*fps + 0.1/elapsed
+			}
+			lastFrame = now
+
+			status := fmt.Sprintf("%.1f fps", fps)
+			if paused {
+				status += " (paused)"
+			}
+			gocv.PutText(&next, status, image.Pt(5, 20), gocv.FontHersheySimplex, 0.6, debugPassColor, 2)
+
+			frame.Close()
+			frame = next
 		}
-		fmt.Println(mnv, mxv, mnl, mxl)
 
-		srcMat, err := gocv.ImageToMatRGB(*src)
-		if err != nil {
-			return err
+		if !frame.Empty() {
+			w.IMShow(frame)
 		}
 
-		gocv.Rectangle(&srcMat, image.Rect(mxl.X, mxl.Y, mxl.X+imgX, mxl.Y+imgY), color.RGBA{255, 0, 0, 1}, 2)
-		w.IMShow(srcMat)
-		if w.WaitKey(1) == 113 {
-			break
+		switch w.WaitKey(debugWaitMs) {
+		case debugKeyQuit, debugKeyEscape:
+			return nil
+		case debugKeyPause:
+			paused = !paused
+		case debugKeyStep:
+			if paused {
+				step = true
+			}
 		}
 	}
+}
+
+// detectedFrame captures the bot's window and returns it with the best match of each template drawn on top of it.
+// The caller is responsible for closing the returned Mat.
+func (b *Bot) detectedFrame(ctx context.Context, tmpls []*Template) (gocv.Mat, error) {
+	src := b.CaptureWindow()
+
+	matches, err := b.DetectMany(ctx, src, tmpls)
+	if err != nil {
+		return gocv.NewMat(), err
+	}
+
+	srcMat, err := gocv.ImageToMatRGB(*src)
+	if err != nil {
+		return gocv.NewMat(), fmt.Errorf("failed to convert img to gocv.Mat: %v", err)
+	}
+
+	for _, m := range matches {
+		c := debugFailColor
+		if m.Found {
+			c = debugPassColor
+		}
+
+		Box{Label: fmt.Sprintf("%s %.3f", m.Name, m.Score), Rect: m.Rect(), Color: c}.draw(&srcMat)
+	}
 
-	return nil
+	return srcMat, nil
 }
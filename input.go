@@ -0,0 +1,124 @@
+package gamebot
+
+import (
+	"fmt"
+
+	"github.com/go-vgo/robotgo"
+)
+
+// InputDriver sends keyboard and mouse input to the operating system. Every key press, button press and cursor
+// movement the bot makes goes through its driver, see `(b *Bot) SetInputDriver`.
+//
+// Mouse coordinates given to and returned by a driver are in the operating system's units, see CoordinateTransform.
+// The bot tracks which keys are down, a driver only needs to send the input.
+type InputDriver interface {
+	// KeyDown puts `key` in a down state.
+	KeyDown(key string) error
+	// KeyUp puts `key` in an up state.
+	KeyUp(key string) error
+	// MouseDown puts `btn` in a down state.
+	MouseDown(btn MouseButton) error
+	// MouseUp puts `btn` in an up state.
+	MouseUp(btn MouseButton) error
+	// Move puts the cursor at x, y.
+	Move(x, y int) error
+	// MousePosition returns the x, y coordinates of the cursor.
+	MousePosition() (int, int)
+	// Scroll turns the mouse wheel. Positive y scrolls up and positive x scrolls left.
+	Scroll(x, y int) error
+	// Type types `s` using the operating system's unicode input.
+	Type(s string) error
+}
+
+// InputAction is the kind of an InputEvent.
+type InputAction string
+
+const (
+	KeyDownAction   InputAction = "keydown"
+	KeyUpAction     InputAction = "keyup"
+	MouseDownAction InputAction = "mousedown"
+	MouseUpAction   InputAction = "mouseup"
+	MoveAction      InputAction = "move"
+	ScrollAction    InputAction = "scroll"
+	TypeAction      InputAction = "type"
+)
+
+// InputEvent is a single call made to an InputDriver.
+type InputEvent struct {
+	Action InputAction `json:"action"`
+	// Key is the key or mouse button pressed or released, or the text typed.
+	Key string `json:"key,omitempty"`
+	// X and Y are the position the cursor moved to, or the amount scrolled.
+	X int `json:"x,omitempty"`
+	Y int `json:"y,omitempty"`
+}
+
+// robotgoDriver sends input using robotgo. It is the driver bots use by default.
+type robotgoDriver struct{}
+
+func (robotgoDriver) KeyDown(key string) error {
+	if msg := robotgo.KeyToggle(key, string(Down)); msg != "" {
+		return fmt.Errorf("failed to press %s: %s", key, msg)
+	}
+	return nil
+}
+
+func (robotgoDriver) KeyUp(key string) error {
+	if msg := robotgo.KeyToggle(key, string(Up)); msg != "" {
+		return fmt.Errorf("failed to release %s: %s", key, msg)
+	}
+	return nil
+}
+
+func (robotgoDriver) MouseDown(btn MouseButton) error {
+	if robotgo.Toggle(string(btn), string(Down)) != 0 {
+		return fmt.Errorf("failed to press mouse button %s", btn)
+	}
+	return nil
+}
+
+func (robotgoDriver) MouseUp(btn MouseButton) error {
+	if robotgo.Toggle(string(btn), string(Up)) != 0 {
+		return fmt.Errorf("failed to release mouse button %s", btn)
+	}
+	return nil
+}
+
+func (robotgoDriver) Move(x, y int) error {
+	robotgo.Move(x, y)
+	return nil
+}
+
+func (robotgoDriver) MousePosition() (int, int) {
+	return robotgo.GetMousePos()
+}
+
+func (robotgoDriver) Scroll(x, y int) error {
+	robotgo.Scroll(x, y, 0)
+	return nil
+}
+
+func (robotgoDriver) Type(s string) error {
+	robotgo.TypeStr(s)
+	return nil
+}
+
+// (b *Bot) SetInputDriver sets the driver the bot sends keyboard and mouse input through, e.g. a FakeDriver in tests.
+// If `d` is nil the default driver, which uses robotgo, is used.
+func (b *Bot) SetInputDriver(d InputDriver) {
+	b.config.botRWMut.Lock()
+	defer b.config.botRWMut.Unlock()
+
+	if d == nil {
+		d = robotgoDriver{}
+	}
+	b.config.input = d
+}
+
+// input returns the driver the bot sends input through.
+func (b *Bot) input() InputDriver {
+	b.config.botRWMut.RLock()
+	defer b.config.botRWMut.RUnlock()
+
+	return b.config.input
+}
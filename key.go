@@ -1,6 +1,10 @@
 package gamebot
 
-import "github.com/go-vgo/robotgo"
+import (
+	"context"
+	"strings"
+	"time"
+)
 
 type KeyState string
 
@@ -9,51 +13,200 @@ const (
 	Up   KeyState = "up"
 )
 
+const (
+	// chordGapMin and chordGapMax represent the range of time between pressing each key of a chord.
+	chordGapMin = 10 * time.Millisecond
+	chordGapMax = 40 * time.Millisecond
+)
+
 // (b *Bot) PressKey toggles a key on the keyboard. This will put the key in a down state until ReleaseKey is called.
+// Pressing a key that is already down, e.g. by another goroutine, keeps it down until it has been released as many
+// times as it was pressed. Mouse buttons, e.g. KeyMouseLeft, are pressed with `(b *Bot) MousePress`.
+// ErrBotPaused or ErrBotKilled is returned if the bot may not send input, see `(b *Bot) Pause`. The press waits for, or
+// is refused by, the bot's RateLimit, see `(b *Bot) SetRateLimit`, and its FocusGuard, see `(b *Bot) SetFocusGuard`.
+//
+// The key name is checked with ParseKey, so aliases such as "return" may be used. An UnknownKeyError is returned, and
+// nothing is pressed, if the key is not supported.
 // Reference https://github.com/go-vgo/robotgo/blob/master/docs/keys.md for full list of keycodes.
-func (b *Bot) PressKey(key string) {
+func (b *Bot) PressKey(key Key) error {
+	return b.pressKey(context.Background(), key)
+}
+
+// pressKey presses `key`, see `(b *Bot) PressKey`. If `ctx` is cancelled while waiting on the bot's RateLimit nothing is
+// pressed and the context's error is returned.
+func (b *Bot) pressKey(ctx context.Context, key Key) error {
+	key, err := ParseKey(string(key))
+	if err != nil {
+		return err
+	}
+	if btn, ok := key.mouseButton(); ok {
+		return b.mousePress(ctx, btn)
+	}
+	if err := b.checkInput(); err != nil {
+		return err
+	}
+	if err := b.guardFocus(); err != nil {
+		return err
+	}
+	// A key that is already down is not pressed again, so it does not count towards the rate limit.
+	if !b.isDown(string(key)) {
+		if err := b.limitInput(ctx, keyAction, string(key)); err != nil {
+			return err
+		}
+	}
+
 	b.config.botRWMut.Lock()
 	defer b.config.botRWMut.Unlock()
 
-	robotgo.KeyToggle(key)
-	b.config.keysDown[key] = true
+	if err := b.inputErr(); err != nil {
+		return err
+	}
+	if b.config.keysDown[string(key)] == 0 {
+		if err := b.config.input.KeyDown(string(key)); err != nil {
+			return err
+		}
+	}
+	b.config.keysDown[string(key)]++
+
+	return nil
 }
 
-// (b *Bot) ReleaseKey toggles a key on the keyboard. This will put the key in a down state until ReleaseKey is called.
+// (b *Bot) ReleaseKey toggles a key on the keyboard. This will put the key in an up state once it has been released
+// as many times as it was pressed. Mouse buttons, e.g. KeyMouseLeft, are released with `(b *Bot) MouseRelease`.
+// An UnknownKeyError is returned if the key is not supported.
 // Reference https://github.com/go-vgo/robotgo/blob/master/docs/keys.md for full list of keycodes.
-func (b *Bot) ReleaseKey(key string) {
+func (b *Bot) ReleaseKey(key Key) error {
+	key, err := ParseKey(string(key))
+	if err != nil {
+		return err
+	}
+	if btn, ok := key.mouseButton(); ok {
+		return b.MouseRelease(btn)
+	}
+
 	b.config.botRWMut.Lock()
 	defer b.config.botRWMut.Unlock()
 
-	robotgo.KeyToggle(key, string(Up))
-	delete(b.config.keysDown, key)
+	if b.config.keysDown[string(key)] > 1 {
+		b.config.keysDown[string(key)]--
+		return nil
+	}
+
+	delete(b.config.keysDown, string(key))
+	return b.config.input.KeyUp(string(key))
 }
 
 // (b *Bot) IsKeyDown returns true is the specified key is in a down state.
 //
 // Note that mouse keys are prefixed with the string mouse e.g. mouseleft, mouseright, mousecenter
-func (b *Bot) IsKeyDown(key string) bool {
+func (b *Bot) IsKeyDown(key Key) bool {
+	if k, err := ParseKey(string(key)); err == nil {
+		key = k
+	}
+
+	b.config.botRWMut.RLock()
+	defer b.config.botRWMut.RUnlock()
+
+	return b.config.keysDown[string(key)] > 0
+}
+
+// isDown returns true if `name`, as it is recorded in the bot's down keys, e.g. "a", "mouseleft" or "padA", is in a
+// down state.
+func (b *Bot) isDown(name string) bool {
 	b.config.botRWMut.RLock()
 	defer b.config.botRWMut.RUnlock()
 
-	_, ok := b.config.keysDown[key]
-	return ok
+	return b.config.keysDown[name] > 0
 }
 
-// (b *Bot) KeyTap will press and release a key.
-// The `args` parameter represents special characters that may need to be pressed alongside the primary key, e.g shift.
+// (b *Bot) KeyTap will press and release a key, or a chord of keys joined with a `+`, e.g. "ctrl+shift+s".
+// The keys of a chord are pressed in order, the last key is held for a random duration drawn from the bot's key timing,
+// see `(b *Bot) SetKeyTiming`, then the keys are released in reverse order. KeyTap blocks until the keys are released,
+// use `(b *Bot) HoldKey` to tap a key without blocking.
+//
+// An UnknownKeyError is returned, and nothing is pressed, if any key of the chord is not supported.
 // Reference https://github.com/go-vgo/robotgo/blob/master/docs/keys.md for full list of keycodes.
-func (b *Bot) KeyTap(key string) {
-	robotgo.KeyToggle(key)
-	b.MilliSleep(300)
-	robotgo.KeyToggle(key, string(Up))
+func (b *Bot) KeyTap(key string) error {
+	keys, err := parseChord(key)
+	if err != nil {
+		return err
+	}
+
+	return b.tapKeys(context.Background(), keys, b.KeyTiming().Hold)
+}
+
+// tapKeys presses each of `keys` in order, holds them for a duration drawn from `hold` and releases them in reverse order.
+// If a key cannot be pressed the keys already pressed are released and the error is returned.
+func (b *Bot) tapKeys(ctx context.Context, keys []Key, hold DurationRange) error {
+	if err := b.pressKeys(ctx, keys); err != nil {
+		return err
+	}
+
+	time.Sleep(hold.draw(b.rand()))
+
+	return b.releaseKeys(keys)
+}
+
+// pressKeys presses each of `keys` in order with a short random pause between them. If a key cannot be pressed the keys
+// already pressed are released and the error is returned, including the context's error if `ctx` is cancelled while
+// waiting on the bot's RateLimit.
+func (b *Bot) pressKeys(ctx context.Context, keys []Key) error {
+	for i, k := range keys {
+		if i > 0 {
+			time.Sleep(b.randomDuration(chordGapMin, chordGapMax))
+		}
+		if err := b.pressKey(ctx, k); err != nil {
+			b.releaseKeys(keys[:i])
+			return err
+		}
+	}
+
+	return nil
+}
+
+// releaseKeys releases each of `keys` in reverse order and returns the first error.
+func (b *Bot) releaseKeys(keys []Key) error {
+	var firstErr error
+	for i := len(keys) - 1; i >= 0; i-- {
+		if err := b.ReleaseKey(keys[i]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// parseChord splits a chord such as "ctrl+shift+s" into the keys that make it up. A `+` key, or the keypad's "num+",
+// can be given at the end of a chord, e.g. "shift++". An UnknownKeyError is returned if any key is not supported.
+func parseChord(chord string) ([]Key, error) {
+	names := []string{"+"}
+	if chord != "+" {
+		names = strings.Split(chord, "+")
+		switch {
+		case strings.HasSuffix(chord, "++"):
+			names = append(names[:len(names)-2], "+")
+		case strings.HasSuffix(chord, "num+"):
+			names = append(names[:len(names)-2], "num+")
+		}
+	}
+
+	keys := make([]Key, 0, len(names))
+	for _, name := range names {
+		k, err := ParseKey(name)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+
+	return keys, nil
 }
 
 // (b *Bot) KeysDown returns a slice of keys currently in the down position.
 // If there are no keys in a down state this function returns an empty slice.
 func (b *Bot) KeysDown() []string {
-	b.config.botRWMut.Lock()
-	defer b.config.botRWMut.Unlock()
+	b.config.botRWMut.RLock()
+	defer b.config.botRWMut.RUnlock()
 
 	keys := make([]string, 0)
 	for k := range b.config.keysDown {
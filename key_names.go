@@ -0,0 +1,191 @@
+package gamebot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Key is the name of a keyboard key or mouse button. Single characters such as "w" or "1" name the key that types them.
+// Mouse buttons are prefixed with the string mouse, e.g. KeyMouseLeft, so they can be pressed by the key functions and
+// are not confused with the keyboard's arrow keys.
+type Key string
+
+const (
+	KeyBackspace   Key = "backspace"
+	KeyDelete      Key = "delete"
+	KeyEnter       Key = "enter"
+	KeyTab         Key = "tab"
+	KeyEscape      Key = "esc"
+	KeySpace       Key = "space"
+	KeyUp          Key = "up"
+	KeyDown        Key = "down"
+	KeyLeft        Key = "left"
+	KeyRight       Key = "right"
+	KeyHome        Key = "home"
+	KeyEnd         Key = "end"
+	KeyPageUp      Key = "pageup"
+	KeyPageDown    Key = "pagedown"
+	KeyInsert      Key = "insert"
+	KeyPrintScreen Key = "printscreen"
+	KeyMenu        Key = "menu"
+	KeyCapsLock    Key = "capslock"
+	KeyNumLock     Key = "num_lock"
+
+	KeyShift  Key = "shift"
+	KeyLShift Key = "lshift"
+	KeyRShift Key = "rshift"
+	KeyCtrl   Key = "ctrl"
+	KeyLCtrl  Key = "lctrl"
+	KeyRCtrl  Key = "rctrl"
+	KeyAlt    Key = "alt"
+	KeyLAlt   Key = "lalt"
+	KeyRAlt   Key = "ralt"
+	KeyCmd    Key = "cmd"
+	KeyLCmd   Key = "lcmd"
+	KeyRCmd   Key = "rcmd"
+
+	KeyF1  Key = "f1"
+	KeyF2  Key = "f2"
+	KeyF3  Key = "f3"
+	KeyF4  Key = "f4"
+	KeyF5  Key = "f5"
+	KeyF6  Key = "f6"
+	KeyF7  Key = "f7"
+	KeyF8  Key = "f8"
+	KeyF9  Key = "f9"
+	KeyF10 Key = "f10"
+	KeyF11 Key = "f11"
+	KeyF12 Key = "f12"
+
+	KeyNum0 Key = "num0"
+	KeyNum1 Key = "num1"
+	KeyNum2 Key = "num2"
+	KeyNum3 Key = "num3"
+	KeyNum4 Key = "num4"
+	KeyNum5 Key = "num5"
+	KeyNum6 Key = "num6"
+	KeyNum7 Key = "num7"
+	KeyNum8 Key = "num8"
+	KeyNum9 Key = "num9"
+
+	KeyMouseLeft   Key = "mouseleft"
+	KeyMouseRight  Key = "mouseright"
+	KeyMouseCenter Key = "mousecenter"
+)
+
+// supportedKeys are the names of every key that can be pressed other than single characters. They are the key names
+// of robotgo's key table, see key/goKey.h, other than the aliases listed in keyAliases, plus the mouse buttons.
+var supportedKeys = map[Key]bool{
+	KeyBackspace: true, KeyDelete: true, KeyEnter: true, KeyTab: true, KeyEscape: true, KeySpace: true,
+	KeyUp: true, KeyDown: true, KeyLeft: true, KeyRight: true, KeyHome: true, KeyEnd: true,
+	KeyPageUp: true, KeyPageDown: true, KeyInsert: true, KeyPrintScreen: true, KeyMenu: true,
+	KeyCapsLock: true, KeyNumLock: true,
+	KeyShift: true, KeyLShift: true, KeyRShift: true, KeyCtrl: true, KeyLCtrl: true, KeyRCtrl: true,
+	KeyAlt: true, KeyLAlt: true, KeyRAlt: true, KeyCmd: true, KeyLCmd: true, KeyRCmd: true,
+	KeyF1: true, KeyF2: true, KeyF3: true, KeyF4: true, KeyF5: true, KeyF6: true,
+	KeyF7: true, KeyF8: true, KeyF9: true, KeyF10: true, KeyF11: true, KeyF12: true,
+	"f13": true, "f14": true, "f15": true, "f16": true, "f17": true, "f18": true,
+	"f19": true, "f20": true, "f21": true, "f22": true, "f23": true, "f24": true,
+	KeyNum0: true, KeyNum1: true, KeyNum2: true, KeyNum3: true, KeyNum4: true,
+	KeyNum5: true, KeyNum6: true, KeyNum7: true, KeyNum8: true, KeyNum9: true,
+	"numpad_0": true, "numpad_1": true, "numpad_2": true, "numpad_3": true, "numpad_4": true,
+	"numpad_5": true, "numpad_6": true, "numpad_7": true, "numpad_8": true, "numpad_9": true, "numpad_lock": true,
+	"num.": true, "num+": true, "num-": true, "num*": true, "num/": true,
+	"num_clear": true, "num_enter": true, "num_equal": true,
+	"audio_mute": true, "audio_vol_down": true, "audio_vol_up": true, "audio_play": true, "audio_stop": true,
+	"audio_pause": true, "audio_prev": true, "audio_next": true, "audio_rewind": true, "audio_forward": true,
+	"audio_repeat": true, "audio_random": true,
+	"lights_mon_up": true, "lights_mon_down": true, "lights_kbd_toggle": true, "lights_kbd_up": true,
+	"lights_kbd_down": true,
+	KeyMouseLeft: true, KeyMouseRight: true, KeyMouseCenter: true,
+}
+
+// keyAliases maps friendlier or platform specific names of keys to the names the bot uses.
+var keyAliases = map[string]Key{
+	"escape":      KeyEscape,
+	"return":      KeyEnter,
+	"del":         KeyDelete,
+	"ins":         KeyInsert,
+	"bksp":        KeyBackspace,
+	"spacebar":    KeySpace,
+	"pgup":        KeyPageUp,
+	"pgdn":        KeyPageDown,
+	"print":       KeyPrintScreen,
+	"prtsc":       KeyPrintScreen,
+	"caps":        KeyCapsLock,
+	"numlock":     KeyNumLock,
+	"arrowup":     KeyUp,
+	"arrowdown":   KeyDown,
+	"arrowleft":   KeyLeft,
+	"arrowright":  KeyRight,
+	"control":     KeyCtrl,
+	"ctl":         KeyCtrl,
+	"option":      KeyAlt,
+	"opt":         KeyAlt,
+	"command":     KeyCmd,
+	"meta":        KeyCmd,
+	"super":       KeyCmd,
+	"win":         KeyCmd,
+	"windows":     KeyCmd,
+	"right_shift": KeyRShift,
+	"lmb":         KeyMouseLeft,
+	"rmb":         KeyMouseRight,
+	"mmb":         KeyMouseCenter,
+	"mouse1":      KeyMouseLeft,
+	"mouse2":      KeyMouseRight,
+	"mouse3":      KeyMouseCenter,
+}
+
+// UnknownKeyError is returned when a key name is not a key the bot can press.
+type UnknownKeyError struct {
+	Name string
+}
+
+func (e *UnknownKeyError) Error() string {
+	return fmt.Sprintf("UnknownKeyError: %q is not a supported key", e.Name)
+}
+
+func (e *UnknownKeyError) Is(tgt error) bool {
+	_, ok := tgt.(*UnknownKeyError)
+	return ok
+}
+
+// NewUnknownKeyError is returned when a key name is not a key the bot can press.
+func NewUnknownKeyError(name string) *UnknownKeyError {
+	return &UnknownKeyError{
+		Name: name,
+	}
+}
+
+// ParseKey returns the Key named `name`. Names are not case sensitive, except for single characters, and may be one
+// of the aliases such as "return" for KeyEnter or "lmb" for KeyMouseLeft. An UnknownKeyError is returned if `name` is
+// not a key the bot can press.
+func ParseKey(name string) (Key, error) {
+	if r := []rune(name); len(r) == 1 {
+		if keys, ok := charKeys(r[0]); ok {
+			// Whitespace is named, e.g. " " is KeySpace. Other characters are pressed as they are.
+			if len(keys) == 1 {
+				return Key(keys[0]), nil
+			}
+			return Key(name), nil
+		}
+	}
+
+	lower := strings.ToLower(strings.TrimSpace(name))
+	if supportedKeys[Key(lower)] {
+		return Key(lower), nil
+	}
+	if k, ok := keyAliases[lower]; ok {
+		return k, nil
+	}
+
+	return "", NewUnknownKeyError(name)
+}
+
+// mouseButton returns the mouse button `k` names, or false if `k` is a keyboard key.
+func (k Key) mouseButton() (MouseButton, bool) {
+	if !strings.HasPrefix(string(k), "mouse") {
+		return "", false
+	}
+	return MouseButton(strings.TrimPrefix(string(k), "mouse")), true
+}
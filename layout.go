@@ -0,0 +1,214 @@
+package gamebot
+
+import (
+	"fmt"
+	"image"
+	"sync"
+)
+
+// AnchorNotFoundError is returned when a layout's anchor template cannot be found in the window.
+type AnchorNotFoundError struct {
+	Score     float32
+	Threshold float32
+}
+
+func (e *AnchorNotFoundError) Error() string {
+	return fmt.Sprintf("AnchorNotFoundError: best anchor score %f did not pass the threshold %f", e.Score, e.Threshold)
+}
+
+func (e *AnchorNotFoundError) Is(tgt error) bool {
+	_, ok := tgt.(*AnchorNotFoundError)
+	return ok
+}
+
+// NewAnchorNotFoundError is returned when a layout's anchor template cannot be found in the window.
+func NewAnchorNotFoundError(score, threshold float32) *AnchorNotFoundError {
+	return &AnchorNotFoundError{
+		Score:     score,
+		Threshold: threshold,
+	}
+}
+
+// LayoutElementNotFoundError is returned when a point or region has not been added to a layout.
+type LayoutElementNotFoundError struct {
+	Name string
+}
+
+func (e *LayoutElementNotFoundError) Error() string {
+	return fmt.Sprintf("LayoutElementNotFoundError: %s is not part of the layout", e.Name)
+}
+
+func (e *LayoutElementNotFoundError) Is(tgt error) bool {
+	_, ok := tgt.(*LayoutElementNotFoundError)
+	return ok
+}
+
+// NewLayoutElementNotFoundError is returned when a point or region has not been added to a layout.
+func NewLayoutElementNotFoundError(name string) *LayoutElementNotFoundError {
+	return &LayoutElementNotFoundError{
+		Name: name,
+	}
+}
+
+// Layout describes named points and regions of a game's UI relative to an anchor template, e.g. the
+// portrait of a merchant whose window can be dragged around the screen.
+//
+// The anchor is found with the bot's template matching and its location is cached. Each call to Resolve
+// checks the anchor is still at the cached location and only searches the whole window again if it has moved.
+type Layout struct {
+	mut sync.RWMutex
+
+	bot    *Bot
+	anchor *Template
+
+	points  map[string]image.Point
+	regions map[string]image.Rectangle
+
+	resolved  bool
+	anchorLoc image.Point
+}
+
+// (b *Bot) NewLayout creates a layout relative to the `anchor` template. The anchor is found when its match
+// score passes the template's threshold.
+func (b *Bot) NewLayout(anchor *Template) *Layout {
+	return &Layout{
+		bot:     b,
+		anchor:  anchor,
+		points:  make(map[string]image.Point),
+		regions: make(map[string]image.Rectangle),
+	}
+}
+
+// (l *Layout) AddPoint adds a named point `offset` pixels from the top-left corner of the anchor.
+func (l *Layout) AddPoint(name string, offset image.Point) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	l.points[name] = offset
+}
+
+// (l *Layout) AddRegion adds a named region. The `rect` is relative to the top-left corner of the anchor.
+func (l *Layout) AddRegion(name string, rect image.Rectangle) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	l.regions[name] = rect
+}
+
+// (l *Layout) Resolve captures the bot's window and locates the anchor. See `(l *Layout) ResolveFrame`.
+func (l *Layout) Resolve() error {
+	return l.ResolveFrame(l.bot.CaptureWindow())
+}
+
+// (l *Layout) ResolveFrame locates the anchor within `frame`. If the anchor is still where it was last found the
+// cached location is kept, otherwise the whole frame is searched. An AnchorNotFoundError is returned if the anchor
+// cannot be found, in which case the layout is no longer resolved.
+func (l *Layout) ResolveFrame(frame *image.Image) error {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	fb := (*frame).Bounds()
+
+	if l.resolved {
+		cached := image.Rectangle{Min: l.anchorLoc, Max: l.anchorLoc.Add(l.anchor.Size())}.Add(fb.Min)
+		if cached.In(fb) {
+			crop := cropImage(*frame, cached)
+			m, err := l.bot.detectIn(&crop, l.anchor, image.Rectangle{})
+			if err != nil {
+				return err
+			}
+			if m.Found {
+				return nil
+			}
+		}
+	}
+
+	l.resolved = false
+
+	m, err := l.bot.Detect(frame, l.anchor)
+	if err != nil {
+		return err
+	}
+	if !m.Found {
+		return NewAnchorNotFoundError(m.Score, l.anchor.Threshold)
+	}
+
+	l.anchorLoc = m.Location
+	l.resolved = true
+	return nil
+}
+
+// (l *Layout) Invalidate clears the cached anchor location so the next call to Resolve searches the whole window.
+func (l *Layout) Invalidate() {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	l.resolved = false
+}
+
+// (l *Layout) Anchor returns the location of the top-left corner of the anchor within the window and true,
+// or false if the layout has not been resolved.
+func (l *Layout) Anchor() (image.Point, bool) {
+	l.mut.RLock()
+	defer l.mut.RUnlock()
+
+	return l.anchorLoc, l.resolved
+}
+
+// (l *Layout) Point returns the location of the named point within the window.
+// An error is returned if the layout has not been resolved or the point does not exist.
+func (l *Layout) Point(name string) (image.Point, error) {
+	l.mut.RLock()
+	defer l.mut.RUnlock()
+
+	if !l.resolved {
+		return image.Point{}, fmt.Errorf("layout has not been resolved")
+	}
+
+	offset, ok := l.points[name]
+	if !ok {
+		return image.Point{}, NewLayoutElementNotFoundError(name)
+	}
+
+	return l.anchorLoc.Add(offset), nil
+}
+
+// (l *Layout) ScreenPoint returns the location of the named point on the screen, ready to be passed to the bot's mouse functions.
+// An error is returned if the layout has not been resolved or the point does not exist.
+func (l *Layout) ScreenPoint(name string) (image.Point, error) {
+	pt, err := l.Point(name)
+	if err != nil {
+		return image.Point{}, err
+	}
+
+	return l.bot.WindowToScreen(pt), nil
+}
+
+// (l *Layout) Region returns the area of the named region within the window.
+// An error is returned if the layout has not been resolved or the region does not exist.
+func (l *Layout) Region(name string) (image.Rectangle, error) {
+	l.mut.RLock()
+	defer l.mut.RUnlock()
+
+	if !l.resolved {
+		return image.Rectangle{}, fmt.Errorf("layout has not been resolved")
+	}
+
+	rect, ok := l.regions[name]
+	if !ok {
+		return image.Rectangle{}, NewLayoutElementNotFoundError(name)
+	}
+
+	return rect.Add(l.anchorLoc), nil
+}
+
+// (l *Layout) ScreenRegion returns the area of the named region on the screen.
+// An error is returned if the layout has not been resolved or the region does not exist.
+func (l *Layout) ScreenRegion(name string) (image.Rectangle, error) {
+	rect, err := l.Region(name)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+
+	return image.Rectangle{Min: l.bot.WindowToScreen(rect.Min), Max: l.bot.WindowToScreen(rect.Max)}, nil
+}
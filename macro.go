@@ -0,0 +1,292 @@
+package gamebot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"time"
+)
+
+// MacroEvent is a single input of a Macro.
+type MacroEvent struct {
+	InputEvent
+	// At is the time of the input since the first input of the macro.
+	At time.Duration `json:"at"`
+}
+
+// Macro is keyboard and mouse input recorded by `(b *Bot) RecordMacro` that can be replayed by `(b *Bot) PlayMacro`.
+// Cursor positions are in window coordinates, so a macro still lines up when the window has moved.
+type Macro struct {
+	Events []MacroEvent `json:"events"`
+}
+
+// LoadMacro reads a macro saved by `(m *Macro) Save` from the JSON file at `path`.
+func LoadMacro(path string) (*Macro, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Macro{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to decode macro %s: %v", path, err)
+	}
+
+	return m, nil
+}
+
+// (m *Macro) Save writes the macro to the JSON file at `path`.
+func (m *Macro) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// (m *Macro) Duration returns the time between the first and last input of the macro.
+func (m *Macro) Duration() time.Duration {
+	if len(m.Events) == 0 {
+		return 0
+	}
+	return m.Events[len(m.Events)-1].At
+}
+
+// (b *Bot) RecordMacro records the mouse moves, clicks, scrolls and key presses made anywhere on the system, e.g. by
+// the player, until `ctx` is cancelled or the bot is closed, then returns the recording. Input is read from the bot's
+// event source, see `(b *Bot) SetEventSource`. Cursor positions are converted to the bot's window, so the window should
+// not be moved while recording.
+//
+// The keys repeated by the operating system while a key is held are recorded once. If recording is stopped with a
+// hotkey, see `(b *Bot) RegisterHotkey`, the hotkey is part of the recording.
+func (b *Bot) RecordMacro(ctx context.Context) (*Macro, error) {
+	events, stop, err := b.eventSource().Listen()
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+
+	m := &Macro{}
+	down := make(map[string]bool)
+	var start time.Time
+
+	record := func(e HookEvent) {
+		if !recordable(down, e.InputEvent) {
+			return
+		}
+		if len(m.Events) == 0 {
+			start = e.When
+		}
+
+		switch e.Action {
+		case MoveAction, MouseDownAction, MouseUpAction:
+			p := b.ScreenToWindow(image.Pt(b.fromLogical(e.X, e.Y)))
+			e.X, e.Y = p.X, p.Y
+		}
+		m.Events = append(m.Events, MacroEvent{InputEvent: e.InputEvent, At: e.When.Sub(start)})
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Keep the input that was made before recording was stopped but has not been read yet.
+			for {
+				select {
+				case e, ok := <-events:
+					if !ok {
+						return m, nil
+					}
+					record(e)
+				default:
+					return m, nil
+				}
+			}
+		case <-b.config.done:
+			return m, nil
+		case e, ok := <-events:
+			if !ok {
+				return m, nil
+			}
+			record(e)
+		}
+	}
+}
+
+// recordable updates the keys that are `down` and returns false if `e` is a key repeated while it is held, or the
+// release of a key that was pressed before recording started.
+func recordable(down map[string]bool, e InputEvent) bool {
+	key := e.Key
+	switch e.Action {
+	case MouseDownAction, MouseUpAction:
+		key = "mouse" + e.Key
+	case KeyDownAction, KeyUpAction:
+	default:
+		return true
+	}
+
+	switch e.Action {
+	case KeyDownAction, MouseDownAction:
+		if down[key] {
+			return false
+		}
+		down[key] = true
+	default:
+		if !down[key] {
+			return false
+		}
+		delete(down, key)
+	}
+
+	return true
+}
+
+// PlayOptions change how `(b *Bot) PlayMacro` replays a macro. The zero value plays the macro once at the speed it
+// was recorded.
+type PlayOptions struct {
+	// Speed is how many times faster than it was recorded the macro is played, e.g. 2 plays it in half the time.
+	// A speed of 0 plays the macro at the speed it was recorded.
+	Speed float64
+	// Jitter is the fraction the time between inputs randomly changes by, e.g. 0.1 changes each gap by up to 10%, so
+	// the macro never plays exactly the same way twice.
+	Jitter float64
+	// Loops is the number of times the macro is played. 0 plays it once and a negative number plays it until `ctx`
+	// is cancelled.
+	Loops int
+}
+
+// (b *Bot) PlayMacro replays `m` through the bot's key and mouse functions, so playback can be paused, see
+// `(b *Bot) Pause`, and its keys are released by `(b *Bot) ReleaseAll`. Every key and mouse button the macro pressed
+// is released when playback ends. If `opts` is nil the macro is played once at the speed it was recorded.
+//
+// The context's error is returned if `ctx` is cancelled. ErrBotPaused or ErrBotKilled is returned if the bot may not
+// send input, and an error is returned if the macro has an input the bot cannot replay.
+func (b *Bot) PlayMacro(ctx context.Context, m *Macro, opts *PlayOptions) error {
+	o := PlayOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Speed <= 0 {
+		o.Speed = 1
+	}
+	if o.Loops == 0 {
+		o.Loops = 1
+	}
+	if len(m.Events) == 0 {
+		return nil
+	}
+
+	pressed := make(map[string]bool)
+	defer b.releaseMacroKeys(pressed)
+
+	for loop := 0; o.Loops < 0 || loop < o.Loops; loop++ {
+		var prev time.Duration
+		for _, e := range m.Events {
+			gap := float64(e.At-prev) / o.Speed
+			gap *= 1 + o.Jitter*(2*b.rand().Float64()-1)
+			prev = e.At
+
+			if err := sleepContext(ctx, time.Duration(gap)); err != nil {
+				return err
+			}
+			if err := b.checkInput(); err != nil {
+				return err
+			}
+			if err := b.playMacroEvent(ctx, pressed, e.InputEvent); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// playMacroEvent sends the input of `e`, tracking the keys it leaves `pressed`. Typing, and waiting on the bot's
+// RateLimit, stops if `ctx` is cancelled.
+func (b *Bot) playMacroEvent(ctx context.Context, pressed map[string]bool, e InputEvent) error {
+	switch e.Action {
+	case KeyDownAction:
+		if pressed[e.Key] {
+			return nil
+		}
+		if err := b.pressKey(ctx, Key(e.Key)); err != nil {
+			return err
+		}
+		pressed[e.Key] = true
+	case KeyUpAction:
+		if !pressed[e.Key] {
+			return nil
+		}
+		delete(pressed, e.Key)
+		return b.ReleaseKey(Key(e.Key))
+	case MouseDownAction:
+		key := "mouse" + e.Key
+		if pressed[key] {
+			return nil
+		}
+		// The cursor may not be where the button was pressed if some of the moves were not recorded.
+		p := b.WindowToScreen(image.Pt(e.X, e.Y))
+		if x, y := b.MousePosition(); x != p.X || y != p.Y {
+			b.SetCursor(p.X, p.Y)
+		}
+		if err := b.mousePress(ctx, MouseButton(e.Key)); err != nil {
+			return err
+		}
+		pressed[key] = true
+	case MouseUpAction:
+		key := "mouse" + e.Key
+		if !pressed[key] {
+			return nil
+		}
+		delete(pressed, key)
+		return b.MouseRelease(MouseButton(e.Key))
+	case MoveAction:
+		p := b.WindowToScreen(image.Pt(e.X, e.Y))
+		b.SetCursor(p.X, p.Y)
+	case ScrollAction:
+		return b.playScroll(ctx, e.X, e.Y)
+	case TypeAction:
+		return b.TypeText(ctx, e.Key)
+	default:
+		return fmt.Errorf("macro action %q is not supported", e.Action)
+	}
+
+	return nil
+}
+
+// playScroll scrolls by `x`, `y` notches. Positive y scrolls up and positive x scrolls left.
+func (b *Bot) playScroll(ctx context.Context, x, y int) error {
+	scrolls := []struct {
+		direction MouseButton
+		notches   int
+	}{
+		{WheelUp, y},
+		{WheelDown, -y},
+		{WheelLeft, x},
+		{WheelRight, -x},
+	}
+
+	for _, s := range scrolls {
+		if s.notches <= 0 {
+			continue
+		}
+		if err := b.scroll(ctx, s.direction, s.notches); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// releaseMacroKeys releases the keys and mouse buttons a macro left `pressed`.
+func (b *Bot) releaseMacroKeys(pressed map[string]bool) {
+	for key := range pressed {
+		if btn, ok := Key(key).mouseButton(); ok {
+			b.MouseRelease(btn)
+		} else {
+			b.ReleaseKey(Key(key))
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package gamebot
+
+import (
+	"context"
+	"image"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// PathPoint is a single step of a cursor movement.
+type PathPoint struct {
+	image.Point
+	// Delay is how long the cursor rests at the point before moving to the next one.
+	Delay time.Duration
+}
+
+// MotionModel generates the path the cursor follows when the bot moves it from one point to another.
+//
+// Models only calculate the path, they never move the cursor, so they can be tested on their own. Every
+// random decision must be drawn from `r` so a bot with a seeded random source moves the same way each run.
+// The last point of a path must be `to`.
+type MotionModel interface {
+	Path(from, to image.Point, r *rand.Rand) []PathPoint
+}
+
+// DefaultMotionModel returns the motion model bots use until `(b *Bot) SetMotionModel` is called: a Bézier curve
+// with micro-jitter and an occasional overshoot, timed with Fitts' law.
+func DefaultMotionModel() MotionModel {
+	return &FittsMotion{
+		Model: &OvershootMotion{
+			Model: &JitterMotion{
+				Model: &BezierMotion{},
+			},
+		},
+	}
+}
+
+// WindMouse moves the cursor as if it were pulled towards the target by gravity while being pushed around by a random
+// wind. It produces paths that wander, speed up and slow down much like a human hand. The zero value is ready to use.
+//
+// Reference: https://ben.land/post/2021/04/25/windmouse-human-mouse-movement/
+type WindMouse struct {
+	// Gravity is the strength pulling the cursor towards the target. Defaults to 9.
+	Gravity float64
+	// Wind is the strength of the random force. Defaults to 3.
+	Wind float64
+	// MaxStep is the furthest the cursor moves in a single step, in pixels. Defaults to 15.
+	MaxStep float64
+	// TargetArea is the distance from the target, in pixels, at which the wind calms and the cursor slows down. Defaults to 12.
+	TargetArea float64
+	// StepDelay is how long the cursor rests between steps. Defaults to 5ms.
+	StepDelay time.Duration
+}
+
+// windMouseMaxSteps stops a WindMouse path that fails to converge from growing forever.
+const windMouseMaxSteps = 10000
+
+func (m *WindMouse) Path(from, to image.Point, r *rand.Rand) []PathPoint {
+	gravity := orDefault(m.Gravity, 9)
+	wind := orDefault(m.Wind, 3)
+	maxStep := orDefault(m.MaxStep, 15)
+	targetArea := orDefault(m.TargetArea, 12)
+	delay := m.StepDelay
+	if delay <= 0 {
+		delay = 5 * time.Millisecond
+	}
+
+	sqrt3, sqrt5 := math.Sqrt(3), math.Sqrt(5)
+
+	x, y := float64(from.X), float64(from.Y)
+	tx, ty := float64(to.X), float64(to.Y)
+	var vx, vy, wx, wy float64
+
+	var path []PathPoint
+	last := from
+	for i := 0; i < windMouseMaxSteps; i++ {
+		dist := math.Hypot(tx-x, ty-y)
+		if dist < 1 {
+			break
+		}
+
+		w := math.Min(wind, dist)
+		if dist >= targetArea {
+			wx = wx/sqrt3 + (2*r.Float64()-1)*w/sqrt5
+			wy = wy/sqrt3 + (2*r.Float64()-1)*w/sqrt5
+		} else {
+			wx /= sqrt3
+			wy /= sqrt3
+			if maxStep < 3 {
+				maxStep = r.Float64()*3 + 3
+			} else {
+				maxStep /= sqrt5
+			}
+		}
+
+		vx += wx + gravity*(tx-x)/dist
+		vy += wy + gravity*(ty-y)/dist
+
+		if v := math.Hypot(vx, vy); v > maxStep {
+			clipped := maxStep/2 + r.Float64()*maxStep/2
+			vx, vy = vx/v*clipped, vy/v*clipped
+		}
+
+		x, y = x+vx, y+vy
+
+		p := image.Pt(int(math.Round(x)), int(math.Round(y)))
+		if p != last && p != to {
+			path = append(path, PathPoint{Point: p, Delay: delay})
+			last = p
+		}
+	}
+
+	return append(path, PathPoint{Point: to})
+}
+
+// BezierMotion moves the cursor along a cubic Bézier curve whose two control points are placed at random either side
+// of the straight line between the start and the target. The cursor eases in and out so it is slowest at either end.
+// The zero value is ready to use.
+type BezierMotion struct {
+	// Spread is how far the control points may stray from the straight line as a fraction of the distance moved. Defaults to 0.3.
+	Spread float64
+	// Duration is how long the movement takes. Defaults to 150ms plus 0.5ms per pixel moved.
+	Duration time.Duration
+	// Steps is the number of points on the curve. Defaults to one for every 8 pixels moved, between 10 and 100.
+	Steps int
+}
+
+func (m *BezierMotion) Path(from, to image.Point, r *rand.Rand) []PathPoint {
+	spread := orDefault(m.Spread, 0.3)
+
+	p0 := vec{float64(from.X), float64(from.Y)}
+	p3 := vec{float64(to.X), float64(to.Y)}
+	d := p3.sub(p0)
+	dist := d.len()
+	if dist < 1 {
+		return []PathPoint{{Point: to}}
+	}
+
+	steps := m.Steps
+	if steps <= 0 {
+		steps = clampInt(int(dist/8), 10, 100)
+	}
+
+	duration := m.Duration
+	if duration <= 0 {
+		duration = 150*time.Millisecond + time.Duration(dist*0.5*float64(time.Millisecond))
+	}
+
+	// The control points sit a third and two thirds of the way along the line, pushed sideways by a random amount.
+	normal := vec{-d.y / dist, d.x / dist}
+	p1 := p0.add(d.scale(1.0 / 3)).add(normal.scale((2*r.Float64() - 1) * spread * dist))
+	p2 := p0.add(d.scale(2.0 / 3)).add(normal.scale((2*r.Float64() - 1) * spread * dist))
+
+	delay := duration / time.Duration(steps)
+	path := make([]PathPoint, 0, steps)
+	for i := 1; i <= steps; i++ {
+		// smoothstep eases in and out so the points bunch up at either end of the curve.
+		t := float64(i) / float64(steps)
+		t = t * t * (3 - 2*t)
+
+		u := 1 - t
+		p := p0.scale(u * u * u).add(p1.scale(3 * u * u * t)).add(p2.scale(3 * u * t * t)).add(p3.scale(t * t * t))
+		path = append(path, PathPoint{Point: p.point(), Delay: delay})
+	}
+
+	path[len(path)-1] = PathPoint{Point: to}
+	return path
+}
+
+// FittsMotion scales the duration of another model's path using Fitts' law, so long moves to small targets take
+// longer than short moves to large targets. The duration is `A + B * log2(distance / TargetWidth + 1)`.
+type FittsMotion struct {
+	// Model generates the path that is rescaled. Defaults to a BezierMotion.
+	Model MotionModel
+	// A is the time taken by any movement. Defaults to 100ms.
+	A time.Duration
+	// B is the time added for each bit of difficulty. Defaults to 150ms.
+	B time.Duration
+	// TargetWidth is the width of the target in pixels. Defaults to 20.
+	TargetWidth float64
+}
+
+func (m *FittsMotion) Path(from, to image.Point, r *rand.Rand) []PathPoint {
+	model := m.Model
+	if model == nil {
+		model = &BezierMotion{}
+	}
+
+	a, b := m.A, m.B
+	if a <= 0 {
+		a = 100 * time.Millisecond
+	}
+	if b <= 0 {
+		b = 150 * time.Millisecond
+	}
+	width := orDefault(m.TargetWidth, 20)
+
+	path := model.Path(from, to, r)
+
+	var total time.Duration
+	for _, p := range path {
+		total += p.Delay
+	}
+	if total <= 0 {
+		return path
+	}
+
+	dist := math.Hypot(float64(to.X-from.X), float64(to.Y-from.Y))
+	want := a + time.Duration(float64(b)*math.Log2(dist/width+1))
+
+	f := float64(want) / float64(total)
+	for i := range path {
+		path[i].Delay = time.Duration(float64(path[i].Delay) * f)
+	}
+
+	return path
+}
+
+// OvershootMotion sometimes moves past the target before correcting back onto it, the way a person does when
+// moving the mouse quickly over a long distance.
+type OvershootMotion struct {
+	// Model generates the path to the overshoot point and back. Defaults to a BezierMotion.
+	Model MotionModel
+	// Probability is the chance, between 0 and 1, that a movement overshoots. Defaults to 0.3.
+	Probability float64
+	// MinDistance is the shortest movement, in pixels, that may overshoot. Defaults to 100.
+	MinDistance float64
+	// Overshoot is how far past the target the cursor goes as a fraction of the distance moved. Defaults to 0.05.
+	Overshoot float64
+	// Pause is how long the cursor rests at the overshoot point before correcting. Defaults to 60ms.
+	Pause time.Duration
+}
+
+func (m *OvershootMotion) Path(from, to image.Point, r *rand.Rand) []PathPoint {
+	model := m.Model
+	if model == nil {
+		model = &BezierMotion{}
+	}
+
+	probability := orDefault(m.Probability, 0.3)
+	minDistance := orDefault(m.MinDistance, 100)
+	overshoot := orDefault(m.Overshoot, 0.05)
+	pause := m.Pause
+	if pause <= 0 {
+		pause = 60 * time.Millisecond
+	}
+
+	d := vec{float64(to.X - from.X), float64(to.Y - from.Y)}
+	dist := d.len()
+	if dist < minDistance || r.Float64() >= probability {
+		return model.Path(from, to, r)
+	}
+
+	// Overshoot along the direction of travel, drifting a little to one side.
+	normal := vec{-d.y / dist, d.x / dist}
+	over := vec{float64(to.X), float64(to.Y)}.
+		add(d.scale(overshoot * (0.5 + r.Float64()))).
+		add(normal.scale((2*r.Float64() - 1) * overshoot * dist / 2)).
+		point()
+
+	path := model.Path(from, over, r)
+	path[len(path)-1].Delay += pause
+
+	return append(path, model.Path(over, to, r)...)
+}
+
+// JitterMotion adds small random offsets to every point of another model's path except the last,
+// imitating the tremor of a human hand.
+type JitterMotion struct {
+	// Model generates the path that is jittered. Defaults to a BezierMotion.
+	Model MotionModel
+	// Amplitude is the standard deviation of the offsets in pixels. Defaults to 1.
+	Amplitude float64
+}
+
+func (m *JitterMotion) Path(from, to image.Point, r *rand.Rand) []PathPoint {
+	model := m.Model
+	if model == nil {
+		model = &BezierMotion{}
+	}
+	amplitude := orDefault(m.Amplitude, 1)
+
+	path := model.Path(from, to, r)
+	for i := 0; i < len(path)-1; i++ {
+		path[i].Point = path[i].Point.Add(image.Pt(
+			int(math.Round(r.NormFloat64()*amplitude)),
+			int(math.Round(r.NormFloat64()*amplitude)),
+		))
+	}
+
+	return path
+}
+
+// (b *Bot) MotionModel returns the model the bot uses to move the cursor.
+func (b *Bot) MotionModel() MotionModel {
+	b.config.botRWMut.RLock()
+	defer b.config.botRWMut.RUnlock()
+
+	return b.config.motionModel
+}
+
+// (b *Bot) SetMotionModel sets the model the bot uses to move the cursor. If `m` is nil the default model is used.
+func (b *Bot) SetMotionModel(m MotionModel) {
+	b.config.botRWMut.Lock()
+	defer b.config.botRWMut.Unlock()
+
+	if m == nil {
+		m = DefaultMotionModel()
+	}
+	b.config.motionModel = m
+}
+
+// (b *Bot) CursorPath returns the path the bot's motion model would move the cursor along from `from` to `to`,
+// without moving the cursor.
+func (b *Bot) CursorPath(from, to image.Point) []PathPoint {
+	return b.MotionModel().Path(from, to, b.rand())
+}
+
+// followPath moves the cursor through each point of `path`, resting at each for its delay.
+// The points are screen coordinates and are converted to the operating system's units as they are followed.
+// If `ctx` is cancelled, or the bot is paused, the cursor stops where it is and the error is returned. The cursor does
+// not move if the focus guard refuses the end of the path, see `(b *Bot) SetFocusGuard`.
+func (b *Bot) followPath(ctx context.Context, path []PathPoint) error {
+	if len(path) > 0 {
+		if err := b.guardPoint(path[len(path)-1].Point); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range path {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := b.checkInput(); err != nil {
+			return err
+		}
+
+		x, y := b.toLogical(p.X, p.Y)
+		b.input().Move(x, y)
+
+		if err := sleepContext(ctx, p.Delay); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sleepContext pauses for `d` or until `ctx` is cancelled, whichever comes first.
+// The context's error is returned if it was cancelled.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// vec is a two dimensional vector used to calculate paths.
+type vec struct{ x, y float64 }
+
+func (v vec) add(o vec) vec       { return vec{v.x + o.x, v.y + o.y} }
+func (v vec) sub(o vec) vec       { return vec{v.x - o.x, v.y - o.y} }
+func (v vec) scale(f float64) vec { return vec{v.x * f, v.y * f} }
+func (v vec) len() float64        { return math.Hypot(v.x, v.y) }
+func (v vec) point() image.Point  { return image.Pt(int(math.Round(v.x)), int(math.Round(v.y))) }
+
+// orDefault returns `v`, or `def` if `v` is not positive.
+func orDefault(v, def float64) float64 {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
+// clampInt limits `v` to the range min to max.
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
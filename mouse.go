@@ -1,7 +1,10 @@
 package gamebot
 
 import (
+	"context"
 	"fmt"
+	"image"
+	"time"
 
 	"github.com/go-vgo/robotgo"
 )
@@ -10,56 +13,128 @@ type MouseButton string
 
 const (
 	Left       MouseButton = "left"
-	Right      MouseButton = "center"
-	Center     MouseButton = "right"
+	Right      MouseButton = "right"
+	Center     MouseButton = "center"
 	WheelDown  MouseButton = "wheelDown"
 	WheelUp    MouseButton = "wheelUp"
 	WheelLeft  MouseButton = "wheelLeft"
 	WheelRight MouseButton = "wheelRight"
 )
 
+// The x, y coordinates taken and returned by the mouse functions are screen pixels as they appear in a screen capture.
+// On scaled displays they are converted to the operating system's units using the bot's display scale,
+// see `(b *Bot) Transform`. Use `(b *Bot) WindowToScreen` to convert a point within the window, such as the
+// location of a Match, to screen coordinates.
+
 // (b *Bot) MoveCursor simulates moving the cursor from it's current position to the x, y
-// location on the screen. This simulates human-like movement. If you want to move x, y number
-// of pixels from the current mouses position see `(b *Bot) MoveCursorRelative`.
-// (x: 0, y: 0) represents the top left-hand corner of the screen.
+// location on the screen. This simulates human-like movement using the bot's motion model, see
+// `(b *Bot) SetMotionModel`. If you want to move x, y number of pixels from the current mouses
+// position see `(b *Bot) MoveCursorRelative`.
+// (x: 0, y: 0) represents the top left-hand corner of the screen. The move waits for any queued action that is running,
+// see `(b *Bot) Submit`, so the two do not fight over the cursor.
 func (b *Bot) MoveCursor(x, y int) {
-	robotgo.MoveSmooth(x, y, 0.5, 1.0)
+	b.config.executor.running.Lock()
+	defer b.config.executor.running.Unlock()
+
+	mx, my := b.MousePosition()
+	b.followPath(context.Background(), b.CursorPath(image.Pt(mx, my), image.Pt(x, y)))
 }
 
 // (b *Bot) MoveCursorRelative simulates moving the cursor from it's current position
 // by x and y number of pixels. This simulates human-like movement. x represents left and
 // right movement while y represents up and down on the screen.
 func (b *Bot) MoveCursorRelative(x, y int) {
-	robotgo.MoveSmoothRelative(x, y, 0.5, 1.0)
+	mx, my := b.MousePosition()
+	b.MoveCursor(mx+x, my+y)
 }
 
 // (b *Bot) SetCursor puts the cursor at the specified x, y position. This movement is nearly instant
 // and does not simulate human-like movement.
 func (b *Bot) SetCursor(x, y int) {
-	robotgo.Move(x, y)
+	b.setCursor(x, y)
+}
+
+// setCursor puts the cursor at x, y on the screen. ErrBotPaused or ErrBotKilled is returned if the bot may not send
+// input, and the focus guard's error if it refuses the point.
+func (b *Bot) setCursor(x, y int) error {
+	if err := b.checkInput(); err != nil {
+		return err
+	}
+	if err := b.guardPoint(image.Pt(x, y)); err != nil {
+		return err
+	}
+
+	x, y = b.toLogical(x, y)
+	b.input().Move(x, y)
+
+	return nil
 }
 
 // (b *Bot) MoveClick puts the cursor at the specified x, y position then clicks the specified mouse button. This movement is nearly instant
 // and does not simulate human-like movement. Reference https://github.com/go-vgo/robotgo/blob/master/docs/keys.md for keycodes.
-func (b *Bot) MoveCursorClick(x, y int, btn MouseButton, doubleClick bool) {
-	robotgo.MoveClick(x, y, btn, doubleClick)
+// An error is returned, and nothing is clicked, if the cursor cannot be moved, e.g. because the bot is paused.
+func (b *Bot) MoveCursorClick(x, y int, btn MouseButton, doubleClick bool) error {
+	b.config.executor.running.Lock()
+	defer b.config.executor.running.Unlock()
+
+	if err := b.setCursor(x, y); err != nil {
+		return err
+	}
+	return b.Click(btn, doubleClick)
 }
 
 // (b *Bot) MoveCursorSmoothClick puts the cursor at the specified x, y position then clicks the specified mouse button.
 // This movement simulates human-like movement. Reference https://github.com/go-vgo/robotgo/blob/master/docs/keys.md for keycodes.
-func (b *Bot) MoveCursorSmoothClick(x, y int, btn MouseButton, doubleClick bool) {
-	robotgo.MovesClick(x, y, btn, doubleClick)
+// Clicks made at the same time by other goroutines, or by queued actions, wait for this one to finish so their moves are
+// not interleaved. Use `(b *Bot) QueueMoveClick` to choose which of them goes first.
+// An error is returned, and nothing is clicked, if the cursor cannot be moved, e.g. because the bot is paused.
+func (b *Bot) MoveCursorSmoothClick(x, y int, btn MouseButton, doubleClick bool) error {
+	b.config.executor.running.Lock()
+	defer b.config.executor.running.Unlock()
+
+	mx, my := b.MousePosition()
+	if err := b.followPath(context.Background(), b.CursorPath(image.Pt(mx, my), image.Pt(x, y))); err != nil {
+		return err
+	}
+	return b.Click(btn, doubleClick)
 }
 
 // (b *Bot) Click click the specified mouse button at the current location of the cursor.
+// Clicking one of the wheel buttons scrolls a single notch in that direction, or two for a double click.
 // Reference https://github.com/go-vgo/robotgo/blob/master/docs/keys.md for keycodes.
-func (b *Bot) Click(btn MouseButton, doubleClick bool) {
-	robotgo.Click(btn, doubleClick)
+// An error is returned if the button cannot be pressed or released, see `(b *Bot) MousePress`.
+func (b *Bot) Click(btn MouseButton, doubleClick bool) error {
+	if _, _, err := scrollDelta(btn); err == nil {
+		notches := 1
+		if doubleClick {
+			notches = 2
+		}
+		return b.Scroll(btn, notches)
+	}
+
+	clicks := 1
+	if doubleClick {
+		clicks = 2
+	}
+
+	for i := 0; i < clicks; i++ {
+		if i > 0 {
+			time.Sleep(b.randomDuration(doubleClickGapMin, doubleClickGapMax))
+		}
+		if err := b.MousePress(btn); err != nil {
+			return err
+		}
+		if err := b.MouseRelease(btn); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // (b *Bot) MousePosition returns the mouse's current x, y coordinates.
 func (b *Bot) MousePosition() (int, int) {
-	return robotgo.GetMousePos()
+	return b.fromLogical(b.input().MousePosition())
 }
 
 // (b *Bot) GetPixelColor return the color of the pixel at the x, y coordinates of the screen.
@@ -67,24 +142,59 @@ func (b *Bot) GetPixelColor(x, y int) string {
 	return robotgo.GetPixelColor(x, y)
 }
 
-// (b *Bot) MousePress puts the specified mouse button in a down state. To release the button use `(b *Bot) MousePress`.
-func (b *Bot) MousePress(btn MouseButton) {
-	b.config.botRWMut.RLock()
-	defer b.config.botRWMut.RUnlock()
+// (b *Bot) MousePress puts the specified mouse button in a down state. To release the button use `(b *Bot) MouseRelease`.
+// An error is returned if the bot may not send input, see `(b *Bot) Pause`, or the bot's input driver fails to press the button.
+// The press waits for, or is refused by, the bot's RateLimit, see `(b *Bot) SetRateLimit`, and its FocusGuard, see
+// `(b *Bot) SetFocusGuard`.
+func (b *Bot) MousePress(btn MouseButton) error {
+	return b.mousePress(context.Background(), btn)
+}
 
-	robotgo.Toggle(string(btn))
+// mousePress presses `btn`, see `(b *Bot) MousePress`. If `ctx` is cancelled while waiting on the bot's RateLimit
+// nothing is pressed and the context's error is returned.
+func (b *Bot) mousePress(ctx context.Context, btn MouseButton) error {
+	if err := b.checkInput(); err != nil {
+		return err
+	}
+	if err := b.guardCursor(); err != nil {
+		return err
+	}
+	if !b.isDown("mouse" + string(btn)) {
+		if err := b.limitInput(ctx, clickAction, "mouse"+string(btn)); err != nil {
+			return err
+		}
+	}
+
+	b.config.botRWMut.Lock()
+	defer b.config.botRWMut.Unlock()
+
+	if err := b.inputErr(); err != nil {
+		return err
+	}
 
 	mouseButton := fmt.Sprintf("mouse%s", btn)
-	b.config.keysDown[mouseButton] = true
+	if b.config.keysDown[mouseButton] == 0 {
+		if err := b.config.input.MouseDown(btn); err != nil {
+			return err
+		}
+	}
+	b.config.keysDown[mouseButton]++
+
+	return nil
 }
 
-// (b *Bot) MouseRelease puts the specified mouse button in an up state.
-func (b *Bot) MouseRelease(btn MouseButton) {
-	b.config.botRWMut.RLock()
-	defer b.config.botRWMut.RUnlock()
-
-	robotgo.Toggle(string(btn))
+// (b *Bot) MouseRelease puts the specified mouse button in an up state once it has been released as many times as it was pressed.
+// An error is returned if the bot's input driver fails to release the button.
+func (b *Bot) MouseRelease(btn MouseButton) error {
+	b.config.botRWMut.Lock()
+	defer b.config.botRWMut.Unlock()
 
 	mouseButton := fmt.Sprintf("mouse%s", btn)
+	if b.config.keysDown[mouseButton] > 1 {
+		b.config.keysDown[mouseButton]--
+		return nil
+	}
+
 	delete(b.config.keysDown, mouseButton)
+	return b.config.input.MouseUp(btn)
 }
@@ -0,0 +1,257 @@
+package gamebot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimitMode is what the bot does with input that would exceed its RateLimit.
+type RateLimitMode int
+
+const (
+	// RateLimitBlock waits until the input is within the limit then sends it.
+	RateLimitBlock RateLimitMode = iota
+	// RateLimitReject refuses the input with a RateLimitError.
+	RateLimitReject
+)
+
+// RateLimit caps how quickly the bot sends input, so a bug in a bot's loop cannot flood the game with clicks.
+// A limit of 0 is not enforced, so the zero value does not limit the bot.
+type RateLimit struct {
+	// ClicksPerSecond is the most mouse button presses sent in any one second.
+	ClicksPerSecond int
+	// KeysPerSecond is the most key presses sent in any one second.
+	KeysPerSecond int
+	// APM is the most actions, i.e. clicks, key presses, gamepad button presses and scroll notches, sent in any one minute.
+	APM int
+	// MinRepeatGap is the least time between two presses of the same key or mouse button.
+	MinRepeatGap time.Duration
+	// Mode is what the bot does with input that would exceed the limit.
+	Mode RateLimitMode
+}
+
+// RateLimitStats describe the input the bot's RateLimit has seen, see `(b *Bot) RateLimitStats`.
+type RateLimitStats struct {
+	// Allowed is the number of actions sent.
+	Allowed int `json:"allowed"`
+	// Delayed is the number of actions that were sent late to stay within the limit.
+	Delayed int `json:"delayed"`
+	// Rejected is the number of actions refused with a RateLimitError.
+	Rejected int `json:"rejected"`
+	// Waited is the total time actions were delayed by.
+	Waited time.Duration `json:"waited"`
+	// APM is the number of actions sent in the last minute.
+	APM int `json:"apm"`
+}
+
+// RateLimitError is returned when input would exceed the bot's RateLimit and the limit's mode is RateLimitReject.
+type RateLimitError struct {
+	// Action is the key or mouse button that was refused.
+	Action string
+	// Wait is how long until the action would be within the limit.
+	Wait time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("RateLimitError: %s would exceed the rate limit, retry in %v", e.Action, e.Wait)
+}
+
+func (e *RateLimitError) Is(tgt error) bool {
+	_, ok := tgt.(*RateLimitError)
+	return ok
+}
+
+// NewRateLimitError is returned when input would exceed the bot's RateLimit and the limit's mode is RateLimitReject.
+func NewRateLimitError(action string, wait time.Duration) *RateLimitError {
+	return &RateLimitError{
+		Action: action,
+		Wait:   wait,
+	}
+}
+
+// actionKind is the kind of input counted by a rateLimiter.
+type actionKind int
+
+const (
+	clickAction actionKind = iota
+	keyAction
+	scrollAction
+	buttonAction
+)
+
+// rateLimiter enforces a RateLimit. It has its own lock so a blocked action does not hold up the rest of the bot.
+type rateLimiter struct {
+	mut sync.Mutex
+
+	limit RateLimit
+	stats RateLimitStats
+
+	// clicks, keys and actions are the times of the actions sent within the limit's windows, oldest first.
+	clicks  []time.Time
+	keys    []time.Time
+	actions []time.Time
+	// last is the time each key or mouse button was last pressed.
+	last map[string]time.Time
+}
+
+// newRateLimiter creates a rateLimiter that does not limit anything.
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		last: make(map[string]time.Time),
+	}
+}
+
+// (b *Bot) SetRateLimit sets the caps on how quickly the bot sends input. The limit applies to every key press, mouse
+// button press and scroll notch, including those of macros and typing. Releases are never limited, so a limited bot
+// never leaves keys pressed. The stats are kept when the limit is changed.
+func (b *Bot) SetRateLimit(l RateLimit) {
+	b.config.limiter.mut.Lock()
+	defer b.config.limiter.mut.Unlock()
+
+	b.config.limiter.limit = l
+}
+
+// (b *Bot) RateLimit returns the caps on how quickly the bot sends input.
+func (b *Bot) RateLimit() RateLimit {
+	b.config.limiter.mut.Lock()
+	defer b.config.limiter.mut.Unlock()
+
+	return b.config.limiter.limit
+}
+
+// (b *Bot) RateLimitStats returns the number of actions the bot's RateLimit has allowed, delayed and rejected, e.g. to
+// be monitored by a bot's debug server.
+func (b *Bot) RateLimitStats() RateLimitStats {
+	l := b.config.limiter
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	l.expire(time.Now())
+	stats := l.stats
+	stats.APM = len(l.actions)
+
+	return stats
+}
+
+// limitInput waits until an action of `kind` pressing `key` is within the bot's RateLimit, or returns a RateLimitError
+// if the limit's mode is RateLimitReject. ErrBotKilled is returned if the bot is closed while waiting, the context's
+// error if `ctx` is cancelled while waiting, and ErrBotPaused or ErrBotKilled if the bot may no longer send input once
+// the wait is over, see `(b *Bot) Pause`. The action is only counted if nil is returned.
+func (b *Bot) limitInput(ctx context.Context, kind actionKind, key string) error {
+	l := b.config.limiter
+
+	var waited time.Duration
+	for {
+		l.mut.Lock()
+		now := time.Now()
+		wait := l.wait(kind, key, now)
+
+		if wait <= 0 {
+			if err := b.checkInput(); err != nil {
+				l.mut.Unlock()
+				return err
+			}
+			l.record(kind, key, now)
+			l.stats.Allowed++
+			if waited > 0 {
+				l.stats.Delayed++
+				l.stats.Waited += waited
+			}
+			l.mut.Unlock()
+			return nil
+		}
+
+		if l.limit.Mode == RateLimitReject {
+			l.stats.Rejected++
+			l.mut.Unlock()
+			return NewRateLimitError(key, wait)
+		}
+		l.mut.Unlock()
+
+		t := time.NewTimer(wait)
+		select {
+		case <-b.config.done:
+			t.Stop()
+			return ErrBotKilled
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		waited += wait
+	}
+}
+
+// wait returns how long until an action of `kind` pressing `key` is within the limit. The caller must hold the lock.
+func (l *rateLimiter) wait(kind actionKind, key string, now time.Time) time.Duration {
+	l.expire(now)
+
+	var wait time.Duration
+	longest := func(d time.Duration) {
+		if d > wait {
+			wait = d
+		}
+	}
+
+	switch kind {
+	case clickAction:
+		longest(windowWait(l.clicks, l.limit.ClicksPerSecond, time.Second, now))
+	case keyAction:
+		longest(windowWait(l.keys, l.limit.KeysPerSecond, time.Second, now))
+	}
+	longest(windowWait(l.actions, l.limit.APM, time.Minute, now))
+
+	if last, ok := l.last[key]; ok && kind != scrollAction && l.limit.MinRepeatGap > 0 {
+		longest(last.Add(l.limit.MinRepeatGap).Sub(now))
+	}
+
+	return wait
+}
+
+// record counts an action of `kind` pressing `key` sent at `now`. The caller must hold the lock.
+func (l *rateLimiter) record(kind actionKind, key string, now time.Time) {
+	switch kind {
+	case clickAction:
+		l.clicks = append(l.clicks, now)
+	case keyAction:
+		l.keys = append(l.keys, now)
+	}
+	l.actions = append(l.actions, now)
+
+	if kind != scrollAction {
+		l.last[key] = now
+	}
+}
+
+// expire forgets the actions that are outside the limit's windows at `now`. The caller must hold the lock.
+func (l *rateLimiter) expire(now time.Time) {
+	l.clicks = expireWindow(l.clicks, now.Add(-time.Second))
+	l.keys = expireWindow(l.keys, now.Add(-time.Second))
+	l.actions = expireWindow(l.actions, now.Add(-time.Minute))
+
+	for key, last := range l.last {
+		if now.Sub(last) >= l.limit.MinRepeatGap {
+			delete(l.last, key)
+		}
+	}
+}
+
+// expireWindow returns `times` without the times before `since`.
+func expireWindow(times []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(times) && !times[i].After(since) {
+		i++
+	}
+	return times[i:]
+}
+
+// windowWait returns how long until another action fits in a window of length `window` that allows `max` actions,
+// given the `times` of the actions within the window. A `max` of 0 allows any number of actions.
+func windowWait(times []time.Time, max int, window time.Duration, now time.Time) time.Duration {
+	if max <= 0 || len(times) < max {
+		return 0
+	}
+	return times[len(times)-max].Add(window).Sub(now)
+}
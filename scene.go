@@ -0,0 +1,359 @@
+package gamebot
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+	"sync"
+
+	"gocv.io/x/gocv"
+)
+
+const (
+	// UnknownScene is the label returned by a SceneClassifier when no scene is similar enough to the frame.
+	UnknownScene = "unknown"
+
+	// sceneThreshold represents the default confidence a scene must reach before it is returned by a SceneClassifier.
+	sceneThreshold = 0.75
+
+	// sceneHistWeight and sceneHashWeight represent how much the histogram comparison and the perceptual hash
+	// contribute to the similarity of a frame and a reference screenshot.
+	sceneHistWeight = 0.5
+	sceneHashWeight = 0.5
+)
+
+// SceneClassifier decides which screen a game is on by comparing frames against labelled reference screenshots.
+//
+// Each label may have any number of reference screenshots and key templates. A frame is compared with a reference
+// using an HSV histogram comparison and a perceptual hash. Key templates are small images that must be present for
+// the scene to match, e.g. a button that only exists on the main menu. A scene missing any of its key templates is
+// never returned, however similar its references are.
+type SceneClassifier struct {
+	mut sync.RWMutex
+
+	bot       *Bot
+	threshold float64
+	scenes    map[string]*scene
+}
+
+type scene struct {
+	references   []*sceneReference
+	keyTemplates []*Template
+}
+
+type sceneReference struct {
+	size  image.Point
+	masks []image.Rectangle
+	hist  gocv.Mat
+	hash  ImageHash
+}
+
+// (b *Bot) NewSceneClassifier creates an empty SceneClassifier. Key templates are matched using the bot's
+// opencv template matching mode.
+func (b *Bot) NewSceneClassifier() *SceneClassifier {
+	return &SceneClassifier{
+		bot:       b,
+		threshold: sceneThreshold,
+		scenes:    make(map[string]*scene),
+	}
+}
+
+// (c *SceneClassifier) Threshold returns the confidence, between 0 and 1, a scene must reach to be returned by Classify.
+func (c *SceneClassifier) Threshold() float64 {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	return c.threshold
+}
+
+// (c *SceneClassifier) SetThreshold sets the confidence, between 0 and 1, a scene must reach to be returned by Classify.
+// If no scene reaches the threshold Classify returns UnknownScene.
+func (c *SceneClassifier) SetThreshold(threshold float64) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.threshold = threshold
+}
+
+// (c *SceneClassifier) AddReference adds a reference screenshot for the scene `label`.
+//
+// The `masks` parameter is a list of areas of the screenshot to ignore, such as timers, chat boxes
+// or player names that change between frames of the same scene.
+func (c *SceneClassifier) AddReference(label string, img *image.Image, masks ...image.Rectangle) error {
+	b := (*img).Bounds()
+	size := image.Pt(b.Dx(), b.Dy())
+
+	masked := maskImage(*img, masks)
+	hist, err := hsvHistogram(masked, masks)
+	if err != nil {
+		return err
+	}
+
+	ref := &sceneReference{
+		size:  size,
+		masks: masks,
+		hist:  hist,
+		hash:  DifferenceHash(masked),
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.scene(label).references = append(c.scene(label).references, ref)
+	return nil
+}
+
+// (c *SceneClassifier) AddKeyTemplate adds a template that must be found in a frame for it to be classified as `label`.
+// The template is found if its match score passes the template's threshold. A key template may be shared by several
+// scenes, and key templates may share a name. A nil template is ignored.
+func (c *SceneClassifier) AddKeyTemplate(label string, tmpl *Template) {
+	if tmpl == nil {
+		return
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.scene(label).keyTemplates = append(c.scene(label).keyTemplates, tmpl)
+}
+
+// scene returns the scene for `label`, creating it if it does not exist. The caller must hold the write lock.
+func (c *SceneClassifier) scene(label string) *scene {
+	s, ok := c.scenes[label]
+	if !ok {
+		s = &scene{}
+		c.scenes[label] = s
+	}
+	return s
+}
+
+// (c *SceneClassifier) Labels returns the sorted labels of every scene known to the classifier.
+func (c *SceneClassifier) Labels() []string {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	return c.sortedLabels()
+}
+
+// (c *SceneClassifier) ClassifyWindow captures the bot's window and classifies it. See `(c *SceneClassifier) Classify`.
+func (c *SceneClassifier) ClassifyWindow() (string, float64, error) {
+	return c.Classify(c.bot.CaptureWindow())
+}
+
+// (c *SceneClassifier) Classify returns the label of the scene most similar to `frame` and a confidence between 0 and 1.
+// If the best confidence is below the classifier's threshold UnknownScene is returned along with that confidence.
+//
+// A scene's confidence is the similarity of its best matching reference screenshot. If the scene has key templates
+// every one of them must be found in the frame, otherwise the scene's confidence is 0. A scene with key templates
+// but no reference screenshots has a confidence of 1 when all of its key templates are found.
+func (c *SceneClassifier) Classify(frame *image.Image) (string, float64, error) {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	keysFound, err := c.keyTemplatesFound(frame)
+	if err != nil {
+		return UnknownScene, 0, err
+	}
+
+	best, bestConfidence := UnknownScene, 0.0
+	for _, label := range c.sortedLabels() {
+		s := c.scenes[label]
+		if len(s.keyTemplates) > 0 && !keysFound[label] {
+			continue
+		}
+
+		// A scene made up only of key templates is certain once they are all found.
+		confidence := 1.0
+		if len(s.references) > 0 {
+			confidence = 0
+		}
+		for _, ref := range s.references {
+			score, err := ref.similarity(*frame)
+			if err != nil {
+				return UnknownScene, 0, err
+			}
+			if score > confidence {
+				confidence = score
+			}
+		}
+
+		if confidence > bestConfidence {
+			best, bestConfidence = label, confidence
+		}
+	}
+
+	if bestConfidence < c.threshold {
+		return UnknownScene, bestConfidence, nil
+	}
+
+	return best, bestConfidence, nil
+}
+
+// sortedLabels returns the labels in a fixed order so ties are always broken the same way.
+// The caller must hold the read lock.
+func (c *SceneClassifier) sortedLabels() []string {
+	labels := make([]string, 0, len(c.scenes))
+	for label := range c.scenes {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	return labels
+}
+
+// keyTemplatesFound matches every key template against `frame` and returns, for each label with key templates,
+// whether all of them were found. The caller must hold the read lock.
+func (c *SceneClassifier) keyTemplatesFound(frame *image.Image) (map[string]bool, error) {
+	// Key templates are told apart by pointer rather than by name, as templates loaded from different directories,
+	// e.g. menu/ok.png and shop/ok.png, share a name.
+	var tmpls []*Template
+	seen := make(map[*Template]bool)
+	for _, label := range c.sortedLabels() {
+		for _, tmpl := range c.scenes[label].keyTemplates {
+			if !seen[tmpl] {
+				seen[tmpl] = true
+				tmpls = append(tmpls, tmpl)
+			}
+		}
+	}
+
+	allFound := make(map[string]bool)
+	if len(tmpls) == 0 {
+		return allFound, nil
+	}
+
+	matches, err := c.bot.detectTemplates(context.Background(), frame, tmpls)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[*Template]bool, len(tmpls))
+	for i, tmpl := range tmpls {
+		found[tmpl] = matches[i].Found
+	}
+
+	for label, s := range c.scenes {
+		if len(s.keyTemplates) == 0 {
+			continue
+		}
+
+		allFound[label] = true
+		for _, tmpl := range s.keyTemplates {
+			if !found[tmpl] {
+				allFound[label] = false
+				break
+			}
+		}
+	}
+
+	return allFound, nil
+}
+
+// (c *SceneClassifier) Close frees the memory held by the classifier's reference histograms.
+// Key templates are not closed as they may be used elsewhere.
+func (c *SceneClassifier) Close() error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	for _, s := range c.scenes {
+		for _, ref := range s.references {
+			ref.hist.Close()
+		}
+	}
+	c.scenes = make(map[string]*scene)
+
+	return nil
+}
+
+// similarity compares `frame` with the reference screenshot, ignoring the reference's masked areas.
+// The result is between 0 (nothing alike) and 1 (identical).
+func (r *sceneReference) similarity(frame image.Image) (float64, error) {
+	masks := scaleRects(r.masks, r.size, frame.Bounds().Size())
+	masked := maskImage(frame, masks)
+
+	hist, err := hsvHistogram(masked, masks)
+	if err != nil {
+		return 0, err
+	}
+	defer hist.Close()
+
+	histScore := float64(gocv.CompareHist(r.hist, hist, gocv.HistCmpCorrel))
+	if histScore < 0 {
+		histScore = 0
+	}
+
+	hashScore := 1 - float64(r.hash.Distance(DifferenceHash(masked)))/64
+
+	return sceneHistWeight*histScore + sceneHashWeight*hashScore, nil
+}
+
+// hsvHistogram calculates a normalised hue and saturation histogram of `img`, ignoring the `masks` areas.
+// The caller is responsible for closing the returned Mat.
+func hsvHistogram(img image.Image, masks []image.Rectangle) (gocv.Mat, error) {
+	src, err := gocv.ImageToMatRGB(img)
+	if err != nil {
+		return gocv.NewMat(), fmt.Errorf("failed to convert img to gocv.Mat: %v", err)
+	}
+	defer src.Close()
+
+	hsv := gocv.NewMat()
+	defer hsv.Close()
+	gocv.CvtColor(src, &hsv, gocv.ColorBGRToHSV)
+
+	mask := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(255, 0, 0, 0), src.Rows(), src.Cols(), gocv.MatTypeCV8U)
+	defer mask.Close()
+
+	bounds := image.Rect(0, 0, src.Cols(), src.Rows())
+	for _, m := range masks {
+		m = m.Intersect(bounds)
+		if m.Empty() {
+			continue
+		}
+		region := mask.Region(m)
+		region.SetTo(gocv.NewScalar(0, 0, 0, 0))
+		region.Close()
+	}
+
+	hist := gocv.NewMat()
+	gocv.CalcHist([]gocv.Mat{hsv}, []int{0, 1}, mask, &hist, []int{30, 32}, []float64{0, 180, 0, 256}, false)
+	gocv.Normalize(hist, &hist, 0, 1, gocv.NormMinMax)
+
+	return hist, nil
+}
+
+// maskImage returns a copy of `img` with the `masks` areas filled black so they
+// cannot affect comparisons. If there are no masks `img` is returned as is.
+func maskImage(img image.Image, masks []image.Rectangle) image.Image {
+	if len(masks) == 0 {
+		return img
+	}
+
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	for _, m := range masks {
+		draw.Draw(dst, m, image.NewUniform(color.Black), image.Point{}, draw.Src)
+	}
+
+	return dst
+}
+
+// scaleRects scales `rects` measured on an image of size `from` to an image of size `to`.
+func scaleRects(rects []image.Rectangle, from, to image.Point) []image.Rectangle {
+	if from == to || from.X == 0 || from.Y == 0 {
+		return rects
+	}
+
+	scaled := make([]image.Rectangle, len(rects))
+	for i, r := range rects {
+		scaled[i] = image.Rect(
+			r.Min.X*to.X/from.X, r.Min.Y*to.Y/from.Y,
+			r.Max.X*to.X/from.X, r.Max.Y*to.Y/from.Y,
+		)
+	}
+
+	return scaled
+}
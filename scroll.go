@@ -0,0 +1,129 @@
+package gamebot
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"time"
+)
+
+const (
+	// scrollNotchMin and scrollNotchMax represent the range of time between the notches of a scroll.
+	scrollNotchMin = 30 * time.Millisecond
+	scrollNotchMax = 110 * time.Millisecond
+)
+
+// scrollDelta returns the amount robotgo scrolls by for a single notch in `direction`.
+// Positive y scrolls up and positive x scrolls left.
+func scrollDelta(direction MouseButton) (int, int, error) {
+	switch direction {
+	case WheelUp:
+		return 0, 1, nil
+	case WheelDown:
+		return 0, -1, nil
+	case WheelLeft:
+		return 1, 0, nil
+	case WheelRight:
+		return -1, 0, nil
+	}
+
+	return 0, 0, fmt.Errorf("%s is not a scroll direction, use WheelUp, WheelDown, WheelLeft or WheelRight", direction)
+}
+
+// (b *Bot) Scroll turns the mouse wheel `notches` times in `direction` at the current location of the cursor.
+// `direction` must be one of WheelUp, WheelDown, WheelLeft or WheelRight. Each notch is followed by a short random
+// pause, as a wheel turned by hand does not move at a constant speed. ErrBotPaused or ErrBotKilled is returned if the
+// bot may not send input, see `(b *Bot) Pause`. Each notch counts towards the bot's RateLimit.
+func (b *Bot) Scroll(direction MouseButton, notches int) error {
+	return b.scroll(context.Background(), direction, notches)
+}
+
+// scroll turns the mouse wheel, see `(b *Bot) Scroll`. If `ctx` is cancelled while waiting on the bot's RateLimit the
+// remaining notches are not scrolled and the context's error is returned.
+func (b *Bot) scroll(ctx context.Context, direction MouseButton, notches int) error {
+	x, y, err := scrollDelta(direction)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < notches; i++ {
+		if err := b.checkInput(); err != nil {
+			return err
+		}
+		if err := b.guardCursor(); err != nil {
+			return err
+		}
+		if err := b.limitInput(ctx, scrollAction, string(direction)); err != nil {
+			return err
+		}
+
+		b.input().Scroll(x, y)
+		time.Sleep(b.randomDuration(scrollNotchMin, scrollNotchMax))
+	}
+
+	return nil
+}
+
+// (b *Bot) ScrollAt moves the cursor to `p` using the bot's motion model then scrolls, see `(b *Bot) Scroll`.
+// `p` is in screen coordinates. Nothing is scrolled if the cursor cannot be moved, e.g. because the bot is paused or
+// the focus guard refuses `p`, and the error is returned.
+func (b *Bot) ScrollAt(p image.Point, direction MouseButton, notches int) error {
+	if _, _, err := scrollDelta(direction); err != nil {
+		return err
+	}
+
+	b.config.executor.running.Lock()
+	defer b.config.executor.running.Unlock()
+
+	mx, my := b.MousePosition()
+	if err := b.followPath(context.Background(), b.CursorPath(image.Pt(mx, my), p)); err != nil {
+		return err
+	}
+	return b.Scroll(direction, notches)
+}
+
+// (b *Bot) ScrollUntilVisible scrolls the window at `p` one notch at a time in `direction` until `tmpl` is found,
+// e.g. to find an item in a long shop list, and returns the Match. `p` is in screen coordinates.
+//
+// A MatchNotFoundError is returned if `tmpl` has not been found after `maxNotches`, or if scrolling stops changing
+// the window because the end of the list has been reached. If `ctx` is cancelled the context's error is returned.
+// Nothing is scrolled if the cursor cannot be moved to `p`, e.g. because the bot is paused, and the error is returned.
+func (b *Bot) ScrollUntilVisible(ctx context.Context, tmpl *Template, p image.Point, direction MouseButton, maxNotches int) (Match, error) {
+	if _, _, err := scrollDelta(direction); err != nil {
+		return Match{}, err
+	}
+
+	b.config.executor.running.Lock()
+	defer b.config.executor.running.Unlock()
+
+	mx, my := b.MousePosition()
+	if err := b.followPath(ctx, b.CursorPath(image.Pt(mx, my), p)); err != nil {
+		return Match{}, err
+	}
+
+	var last ImageHash
+	for notch := 0; ; notch++ {
+		if err := ctx.Err(); err != nil {
+			return Match{}, err
+		}
+
+		frame := b.CaptureWindow()
+		m, err := b.Detect(frame, tmpl)
+		if err != nil {
+			return Match{}, err
+		}
+		if m.Found {
+			return m, nil
+		}
+
+		hash := DifferenceHash(*frame)
+		if notch == maxNotches || (notch > 0 && hash.Distance(last) == 0) {
+			return m, NewMatchNotFoundError(tmpl.Name, m.Score)
+		}
+		last = hash
+
+		if err := b.scroll(ctx, direction, 1); err != nil {
+			return Match{}, err
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package statemachine
+
+import (
+	"context"
+	"image"
+
+	"github.com/KalebHawkins/gamebot"
+)
+
+// Detector recognises a state from a frame of the bot's window.
+type Detector interface {
+	// Detect returns true if `frame` shows the state.
+	Detect(ctx context.Context, frame *image.Image) (bool, error)
+}
+
+// DetectorFunc is a function used as a Detector.
+type DetectorFunc func(ctx context.Context, frame *image.Image) (bool, error)
+
+func (f DetectorFunc) Detect(ctx context.Context, frame *image.Image) (bool, error) {
+	return f(ctx, frame)
+}
+
+// TemplateDetector detects a state when `tmpl` is found in the frame, see `(b *Bot) Detect`.
+func TemplateDetector(b *gamebot.Bot, tmpl *gamebot.Template) Detector {
+	return DetectorFunc(func(ctx context.Context, frame *image.Image) (bool, error) {
+		m, err := b.Detect(frame, tmpl)
+		if err != nil {
+			return false, err
+		}
+		return m.Found, nil
+	})
+}
+
+// SceneDetector detects a state when `c` classifies the frame as the scene `label`.
+func SceneDetector(c *gamebot.SceneClassifier, label string) Detector {
+	return DetectorFunc(func(ctx context.Context, frame *image.Image) (bool, error) {
+		got, _, err := c.Classify(frame)
+		if err != nil {
+			return false, err
+		}
+		return got == label, nil
+	})
+}
+
+// FingerprintDetector detects a state when the frame matches the fingerprint `name` of `s` within `maxDistance`,
+// see `(s *FingerprintStore) Matches`.
+func FingerprintDetector(s *gamebot.FingerprintStore, name string, maxDistance int) Detector {
+	return DetectorFunc(func(ctx context.Context, frame *image.Image) (bool, error) {
+		return s.Matches(name, frame, maxDistance), nil
+	})
+}
+
+// Pixel is a point of a frame and the range of colors it must have, e.g. the red of a full health bar.
+type Pixel struct {
+	// Point is in window coordinates, measured from the top-left corner of the frame.
+	Point image.Point
+	Color gamebot.ColorRange
+}
+
+// PixelDetector detects a state when every one of `pixels` is within its color range. A pixel outside the frame never
+// matches. Pixel signatures are much cheaper than template matching, so they suit states checked every tick.
+func PixelDetector(pixels ...Pixel) Detector {
+	return DetectorFunc(func(ctx context.Context, frame *image.Image) (bool, error) {
+		bounds := (*frame).Bounds()
+		for _, p := range pixels {
+			pt := p.Point.Add(bounds.Min)
+			if !pt.In(bounds) || !p.Color.Contains((*frame).At(pt.X, pt.Y)) {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
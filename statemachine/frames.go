@@ -0,0 +1,58 @@
+package statemachine
+
+import (
+	"context"
+	"image"
+	"io"
+	"sync"
+
+	"github.com/KalebHawkins/gamebot"
+)
+
+// FrameSource provides the frame a Machine looks at each tick.
+type FrameSource interface {
+	// Frame returns the next frame, or io.EOF once there are no more frames.
+	Frame(ctx context.Context) (*image.Image, error)
+}
+
+// botFrames captures the bot's window each tick.
+type botFrames struct {
+	bot *gamebot.Bot
+}
+
+// BotFrames returns a FrameSource that captures the window of `b`, see `(b *Bot) CaptureWindow`.
+func BotFrames(b *gamebot.Bot) FrameSource {
+	return botFrames{bot: b}
+}
+
+func (f botFrames) Frame(ctx context.Context) (*image.Image, error) {
+	return f.bot.CaptureWindow(), nil
+}
+
+// ScriptedFrames is a FrameSource that returns a fixed list of frames, one per tick, so a Machine can be tested
+// without a game. Once every frame has been returned it returns io.EOF, which stops the machine.
+type ScriptedFrames struct {
+	mut sync.Mutex
+
+	frames []*image.Image
+	next   int
+}
+
+// NewScriptedFrames creates a ScriptedFrames that returns `frames` in order.
+func NewScriptedFrames(frames ...*image.Image) *ScriptedFrames {
+	return &ScriptedFrames{
+		frames: frames,
+	}
+}
+
+func (s *ScriptedFrames) Frame(ctx context.Context) (*image.Image, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.next >= len(s.frames) {
+		return nil, io.EOF
+	}
+	s.next++
+
+	return s.frames[s.next-1], nil
+}
@@ -0,0 +1,518 @@
+// Package statemachine runs a bot as a set of states recognised from the screen, instead of a single loop that
+// switches on the state by hand.
+//
+// Each tick the Machine captures a frame, picks the current state using the detectors of its states, runs the
+// state's actions and takes the first of the state's transitions whose guard passes. Every change of state is
+// recorded in the machine's history.
+package statemachine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/KalebHawkins/gamebot"
+)
+
+const (
+	// defaultTickInterval is the time between the ticks of a machine.
+	defaultTickInterval = 100 * time.Millisecond
+	// defaultMaxHistory is the number of transitions a machine remembers.
+	defaultMaxHistory = 100
+)
+
+// Tick is what a state's actions and guards see of the current tick.
+type Tick struct {
+	// Bot is the bot the machine runs against. It is nil if the machine was created without a bot.
+	Bot *gamebot.Bot
+	// Frame is the frame captured this tick.
+	Frame *image.Image
+	// Number counts the ticks since the machine started, starting at 1.
+	Number int
+	// Time is when the frame was captured.
+	Time time.Time
+	// State is the name of the current state.
+	State string
+	// Entered is when the machine entered the current state.
+	Entered time.Time
+}
+
+// (t *Tick) InState returns how long the machine has been in the current state.
+func (t *Tick) InState() time.Duration {
+	return t.Time.Sub(t.Entered)
+}
+
+// Action is run by the machine while it is in a state, e.g. to click a button. An error stops the machine.
+type Action func(ctx context.Context, t *Tick) error
+
+// Transition moves the machine to another state.
+type Transition struct {
+	// To is the name of the state the transition leads to.
+	To string
+	// Guard must return true for the transition to be taken. A nil guard always passes.
+	Guard func(t *Tick) bool
+	// After is how long the machine must have been in the state before the transition can be taken, e.g. to give
+	// up waiting for a loading screen.
+	After time.Duration
+}
+
+// State is a state of a Machine.
+type State struct {
+	// Name identifies the state. It must be unique within the machine.
+	Name string
+	// Detectors recognise the state from a frame. The state is detected when all of them match. A state without
+	// detectors is only entered through transitions or as the initial state.
+	Detectors []Detector
+	// OnEnter is run once each time the machine enters the state.
+	OnEnter Action
+	// Actions are run in order every tick the machine is in the state.
+	Actions []Action
+	// Transitions are checked in order after the actions, and the first that can be taken is.
+	Transitions []Transition
+	// Settle is how long after entering the state other states are not detected, giving the game time to change
+	// screen after an action, e.g. so the menu is not detected again while it fades out.
+	Settle time.Duration
+	// Timeout is how long the machine may stay in the state before it stops with a StateTimeoutError. A timeout of
+	// 0 never expires.
+	Timeout time.Duration
+	// Final stops the machine once it has entered the state and run its OnEnter action.
+	Final bool
+}
+
+// TransitionReason is why a machine changed state.
+type TransitionReason string
+
+const (
+	// ReasonInitial is the machine entering its initial state because no state was detected.
+	ReasonInitial TransitionReason = "initial"
+	// ReasonDetected is the machine entering a state because its detectors matched the frame.
+	ReasonDetected TransitionReason = "detected"
+	// ReasonTransition is the machine taking one of the current state's transitions.
+	ReasonTransition TransitionReason = "transition"
+)
+
+// TransitionRecord is a change of state recorded in a machine's history.
+type TransitionRecord struct {
+	// From is the state the machine left, or empty if it had no state.
+	From string
+	// To is the state the machine entered.
+	To     string
+	Reason TransitionReason
+	// Tick is the number of the tick the machine changed state in.
+	Tick int
+	// At is when the frame of the tick was captured.
+	At time.Time
+}
+
+// UnknownStateError is returned when a transition or the initial state names a state the machine does not have.
+type UnknownStateError struct {
+	Name string
+}
+
+func (e *UnknownStateError) Error() string {
+	return fmt.Sprintf("UnknownStateError: the machine has no state named %q", e.Name)
+}
+
+func (e *UnknownStateError) Is(tgt error) bool {
+	_, ok := tgt.(*UnknownStateError)
+	return ok
+}
+
+// NewUnknownStateError is returned when a transition or the initial state names a state the machine does not have.
+func NewUnknownStateError(name string) *UnknownStateError {
+	return &UnknownStateError{
+		Name: name,
+	}
+}
+
+// DuplicateStateError is returned when a state is added with the name of a state the machine already has.
+type DuplicateStateError struct {
+	Name string
+}
+
+func (e *DuplicateStateError) Error() string {
+	return fmt.Sprintf("DuplicateStateError: the machine already has a state named %q", e.Name)
+}
+
+func (e *DuplicateStateError) Is(tgt error) bool {
+	_, ok := tgt.(*DuplicateStateError)
+	return ok
+}
+
+// NewDuplicateStateError is returned when a state is added with the name of a state the machine already has.
+func NewDuplicateStateError(name string) *DuplicateStateError {
+	return &DuplicateStateError{
+		Name: name,
+	}
+}
+
+// StateTimeoutError is returned when the machine stays in a state for longer than the state's timeout.
+type StateTimeoutError struct {
+	State   string
+	Elapsed time.Duration
+}
+
+func (e *StateTimeoutError) Error() string {
+	return fmt.Sprintf("StateTimeoutError: the machine has been in %s for %v", e.State, e.Elapsed)
+}
+
+func (e *StateTimeoutError) Is(tgt error) bool {
+	_, ok := tgt.(*StateTimeoutError)
+	return ok
+}
+
+// NewStateTimeoutError is returned when the machine stays in a state for longer than the state's timeout.
+func NewStateTimeoutError(state string, elapsed time.Duration) *StateTimeoutError {
+	return &StateTimeoutError{
+		State:   state,
+		Elapsed: elapsed,
+	}
+}
+
+// Machine runs a set of states against a bot, see `(m *Machine) Run`.
+type Machine struct {
+	mut sync.RWMutex
+
+	bot          *gamebot.Bot
+	frames       FrameSource
+	states       []*State
+	byName       map[string]*State
+	initial      string
+	tickInterval time.Duration
+	maxHistory   int
+	onTransition func(TransitionRecord)
+
+	current string
+	entered time.Time
+	history []TransitionRecord
+}
+
+// New creates a Machine with no states that captures the window of `b` each tick. `b` may be nil if the machine's
+// frames are set with `(m *Machine) SetFrameSource`, e.g. to test the machine with scripted frames.
+func New(b *gamebot.Bot) *Machine {
+	m := &Machine{
+		bot:          b,
+		byName:       make(map[string]*State),
+		tickInterval: defaultTickInterval,
+		maxHistory:   defaultMaxHistory,
+	}
+	if b != nil {
+		m.frames = BotFrames(b)
+	}
+
+	return m
+}
+
+// (m *Machine) AddState adds `s` to the machine. States are detected in the order they are added. A
+// DuplicateStateError is returned if the machine already has a state with the same name.
+func (m *Machine) AddState(s State) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if _, ok := m.byName[s.Name]; ok {
+		return NewDuplicateStateError(s.Name)
+	}
+
+	m.states = append(m.states, &s)
+	m.byName[s.Name] = &s
+	return nil
+}
+
+// (m *Machine) SetInitial sets the state the machine enters when no state is detected on its first ticks.
+// Without an initial state the machine waits until a state is detected.
+func (m *Machine) SetInitial(name string) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	m.initial = name
+}
+
+// (m *Machine) SetFrameSource sets where the machine gets its frames from, e.g. ScriptedFrames in tests.
+func (m *Machine) SetFrameSource(src FrameSource) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	m.frames = src
+}
+
+// (m *Machine) SetTickInterval sets the time between ticks. Defaults to 100ms.
+func (m *Machine) SetTickInterval(d time.Duration) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	m.tickInterval = d
+}
+
+// (m *Machine) SetMaxHistory sets the number of transitions the machine remembers. Defaults to 100.
+func (m *Machine) SetMaxHistory(n int) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	m.maxHistory = n
+	m.trimHistory()
+}
+
+// (m *Machine) OnTransition calls `fn` each time the machine changes state, e.g. to log the machine's progress.
+func (m *Machine) OnTransition(fn func(TransitionRecord)) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	m.onTransition = fn
+}
+
+// (m *Machine) Current returns the name of the state the machine is in, or an empty string if it has no state yet.
+func (m *Machine) Current() string {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+
+	return m.current
+}
+
+// (m *Machine) History returns the machine's most recent changes of state, oldest first.
+func (m *Machine) History() []TransitionRecord {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+
+	return append([]TransitionRecord{}, m.history...)
+}
+
+// (m *Machine) Run ticks the machine until `ctx` is cancelled, a final state is entered, an action, detector or
+// the frame source fails, or a state times out. The history is cleared when the machine starts.
+//
+// nil is returned once a final state has been entered or the frame source has no more frames. If the machine runs
+// against a bot it waits at the start of each tick while the bot is paused, see `(b *Bot) Pause`, and stops with
+// ErrBotKilled once the bot has been killed. An UnknownStateError is returned, before anything runs, if a transition
+// or the initial state names a state the machine does not have.
+func (m *Machine) Run(ctx context.Context) error {
+	if err := m.start(); err != nil {
+		return err
+	}
+
+	for n := 1; ; n++ {
+		if m.bot != nil {
+			if err := m.bot.WaitWhilePaused(ctx); err != nil {
+				return err
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		done, err := m.tick(ctx, n)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		if err := sleepContext(ctx, m.interval()); err != nil {
+			return err
+		}
+	}
+}
+
+// start checks the machine's states and resets its state and history.
+func (m *Machine) start() error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if m.frames == nil {
+		return errors.New("statemachine: the machine has no bot or frame source")
+	}
+	if _, ok := m.byName[m.initial]; m.initial != "" && !ok {
+		return NewUnknownStateError(m.initial)
+	}
+	for _, s := range m.states {
+		for _, t := range s.Transitions {
+			if _, ok := m.byName[t.To]; !ok {
+				return NewUnknownStateError(t.To)
+			}
+		}
+	}
+
+	m.current = ""
+	m.history = nil
+	return nil
+}
+
+// tick runs a single tick of the machine and returns true if the machine has entered a final state.
+func (m *Machine) tick(ctx context.Context, n int) (bool, error) {
+	m.mut.RLock()
+	frames := m.frames
+	m.mut.RUnlock()
+
+	frame, err := frames.Frame(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	t := &Tick{Bot: m.bot, Frame: frame, Number: n, Time: time.Now()}
+	m.mut.RLock()
+	t.State, t.Entered = m.current, m.entered
+	initial := m.initial
+	m.mut.RUnlock()
+
+	detected, err := m.detect(ctx, t)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case detected != "" && detected != t.State:
+		if done, err := m.enter(ctx, t, detected, ReasonDetected); done || err != nil {
+			return done, err
+		}
+	case t.State == "" && initial != "":
+		if done, err := m.enter(ctx, t, initial, ReasonInitial); done || err != nil {
+			return done, err
+		}
+	}
+	if t.State == "" {
+		return false, nil
+	}
+
+	s := m.state(t.State)
+	for _, action := range s.Actions {
+		if err := action(ctx, t); err != nil {
+			return false, err
+		}
+	}
+
+	for _, tr := range s.Transitions {
+		if t.InState() < tr.After || (tr.Guard != nil && !tr.Guard(t)) {
+			continue
+		}
+		return m.enter(ctx, t, tr.To, ReasonTransition)
+	}
+
+	if s.Timeout > 0 && t.InState() >= s.Timeout {
+		return false, NewStateTimeoutError(s.Name, t.InState())
+	}
+
+	return false, nil
+}
+
+// detect returns the name of the state shown by the tick's frame, or an empty string if no state is detected. The
+// current state is kept while its detectors match or it is settling.
+func (m *Machine) detect(ctx context.Context, t *Tick) (string, error) {
+	if current := m.state(t.State); current != nil {
+		if t.InState() < current.Settle {
+			return current.Name, nil
+		}
+		if ok, err := detected(ctx, current, t.Frame); ok || err != nil {
+			return current.Name, err
+		}
+	}
+
+	m.mut.RLock()
+	states := append([]*State{}, m.states...)
+	m.mut.RUnlock()
+
+	for _, s := range states {
+		if s.Name == t.State {
+			continue
+		}
+		if ok, err := detected(ctx, s, t.Frame); ok || err != nil {
+			return s.Name, err
+		}
+	}
+
+	return "", nil
+}
+
+// detected returns true if `s` has detectors and all of them match `frame`.
+func detected(ctx context.Context, s *State, frame *image.Image) (bool, error) {
+	if len(s.Detectors) == 0 {
+		return false, nil
+	}
+
+	for _, d := range s.Detectors {
+		ok, err := d.Detect(ctx, frame)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// enter moves the machine to the state `name`, records the transition and runs the state's OnEnter action. It
+// returns true if the state is final. An UnknownStateError is returned, and the machine stays where it is, if it has
+// no state named `name`, e.g. the target of a transition of a state added while the machine is running.
+func (m *Machine) enter(ctx context.Context, t *Tick, name string, reason TransitionReason) (bool, error) {
+	rec := TransitionRecord{From: t.State, To: name, Reason: reason, Tick: t.Number, At: t.Time}
+
+	m.mut.Lock()
+	s := m.byName[name]
+	if s == nil {
+		m.mut.Unlock()
+		return false, NewUnknownStateError(name)
+	}
+	m.current, m.entered = name, t.Time
+	m.history = append(m.history, rec)
+	m.trimHistory()
+	onTransition := m.onTransition
+	m.mut.Unlock()
+
+	t.State, t.Entered = name, t.Time
+	if onTransition != nil {
+		onTransition(rec)
+	}
+
+	if s.OnEnter != nil {
+		if err := s.OnEnter(ctx, t); err != nil {
+			return false, err
+		}
+	}
+
+	return s.Final, nil
+}
+
+// state returns the state named `name`, or nil if the machine has no such state.
+func (m *Machine) state(name string) *State {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+
+	return m.byName[name]
+}
+
+// interval returns the time between ticks.
+func (m *Machine) interval() time.Duration {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+
+	return m.tickInterval
+}
+
+// trimHistory forgets the oldest transitions beyond the maximum history. The caller must hold the lock.
+func (m *Machine) trimHistory() {
+	if m.maxHistory >= 0 && len(m.history) > m.maxHistory {
+		m.history = append([]TransitionRecord{}, m.history[len(m.history)-m.maxHistory:]...)
+	}
+}
+
+// sleepContext pauses for `d` or until `ctx` is cancelled, whichever comes first.
+// The context's error is returned if it was cancelled.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
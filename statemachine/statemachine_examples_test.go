@@ -0,0 +1,55 @@
+package statemachine_test
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/KalebHawkins/gamebot"
+	"github.com/KalebHawkins/gamebot/statemachine"
+)
+
+func ExampleMachine() {
+	procName := filepath.Base(os.Args[0])
+	b, err := gamebot.NewBot(procName)
+
+	if err != nil {
+		panic(err)
+	}
+	defer b.Close()
+
+	playBtn, err := gamebot.LoadTemplate("play_button.png")
+	if err != nil {
+		panic(err)
+	}
+
+	m := statemachine.New(b)
+	m.AddState(statemachine.State{
+		Name:      "menu",
+		Detectors: []statemachine.Detector{statemachine.TemplateDetector(b, playBtn)},
+		Actions: []statemachine.Action{func(ctx context.Context, t *statemachine.Tick) error {
+			match, err := t.Bot.Detect(t.Frame, playBtn)
+			if err != nil {
+				return err
+			}
+			return t.Bot.ClickMatch(match, gamebot.Left)
+		}},
+		// Give the menu time to close before it can be detected again.
+		Settle: 2 * time.Second,
+	})
+	m.AddState(statemachine.State{
+		Name: "in game",
+		Detectors: []statemachine.Detector{statemachine.PixelDetector(statemachine.Pixel{
+			Point: image.Pt(20, 20),
+			Color: gamebot.ColorRange{Min: color.RGBA{R: 180, A: 255}, Max: color.RGBA{R: 255, G: 60, B: 60, A: 255}},
+		})},
+		Final: true,
+	})
+
+	if err := m.Run(context.Background()); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,269 @@
+package statemachine_test
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KalebHawkins/gamebot"
+	"github.com/KalebHawkins/gamebot/statemachine"
+)
+
+var (
+	red   = color.RGBA{R: 255, A: 255}
+	blue  = color.RGBA{B: 255, A: 255}
+	black = color.RGBA{A: 255}
+)
+
+// frame returns a small frame filled with `c`.
+func frame(c color.RGBA) *image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: c}, image.Point{}, draw.Src)
+
+	var i image.Image = img
+	return &i
+}
+
+// shows detects a frame whose center is `c`.
+func shows(c color.RGBA) statemachine.Detector {
+	return statemachine.PixelDetector(statemachine.Pixel{Point: image.Pt(5, 5), Color: gamebot.ColorRange{Min: c, Max: c}})
+}
+
+// endlessFrames returns the same frame forever.
+type endlessFrames struct {
+	frame *image.Image
+}
+
+func (f endlessFrames) Frame(ctx context.Context) (*image.Image, error) {
+	return f.frame, nil
+}
+
+// newMachine creates a machine without a bot that ticks every millisecond through `frames`.
+func newMachine(t *testing.T, frames statemachine.FrameSource, states ...statemachine.State) *statemachine.Machine {
+	t.Helper()
+
+	m := statemachine.New(nil)
+	m.SetFrameSource(frames)
+	m.SetTickInterval(time.Millisecond)
+	for _, s := range states {
+		if err := m.AddState(s); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+	}
+
+	return m
+}
+
+func TestMachine(t *testing.T) {
+	t.Run("Test detection and transitions", func(t *testing.T) {
+		clicks := 0
+		entered := 0
+		m := newMachine(t,
+			statemachine.NewScriptedFrames(frame(black), frame(red), frame(red), frame(blue), frame(blue)),
+			statemachine.State{
+				Name:      "menu",
+				Detectors: []statemachine.Detector{shows(red)},
+				OnEnter: func(ctx context.Context, t *statemachine.Tick) error {
+					entered++
+					return nil
+				},
+				Actions: []statemachine.Action{func(ctx context.Context, t *statemachine.Tick) error {
+					clicks++
+					return nil
+				}},
+			},
+			statemachine.State{
+				Name:      "loading",
+				Detectors: []statemachine.Detector{shows(blue)},
+				Transitions: []statemachine.Transition{
+					{To: "menu", Guard: func(t *statemachine.Tick) bool { return false }},
+					{To: "playing", Guard: func(t *statemachine.Tick) bool { return t.Number >= 4 }},
+				},
+			},
+			statemachine.State{Name: "playing", Final: true},
+		)
+
+		if err := m.Run(context.Background()); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+
+		if clicks != 2 || entered != 1 {
+			t.Errorf("expected the menu to be entered once and clicked twice, got %d and %d", entered, clicks)
+		}
+		if m.Current() != "playing" {
+			t.Errorf("expected %v, got %v", "playing", m.Current())
+		}
+
+		var got []statemachine.TransitionRecord
+		for _, r := range m.History() {
+			r.At = time.Time{}
+			got = append(got, r)
+		}
+		want := []statemachine.TransitionRecord{
+			{From: "", To: "menu", Reason: statemachine.ReasonDetected, Tick: 2},
+			{From: "menu", To: "loading", Reason: statemachine.ReasonDetected, Tick: 4},
+			{From: "loading", To: "playing", Reason: statemachine.ReasonTransition, Tick: 4},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Test initial state and settling", func(t *testing.T) {
+		m := newMachine(t,
+			statemachine.NewScriptedFrames(frame(black), frame(red), frame(red)),
+			statemachine.State{
+				Name:        "idle",
+				Transitions: []statemachine.Transition{{To: "loading"}},
+			},
+			statemachine.State{Name: "menu", Detectors: []statemachine.Detector{shows(red)}},
+			statemachine.State{Name: "loading", Settle: time.Hour},
+		)
+		m.SetInitial("idle")
+
+		var reasons []statemachine.TransitionReason
+		m.OnTransition(func(r statemachine.TransitionRecord) {
+			reasons = append(reasons, r.Reason)
+		})
+
+		if err := m.Run(context.Background()); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if m.Current() != "loading" {
+			t.Errorf("expected a settling state not to be left, got %v", m.Current())
+		}
+		if want := []statemachine.TransitionReason{statemachine.ReasonInitial, statemachine.ReasonTransition}; !reflect.DeepEqual(reasons, want) {
+			t.Errorf("expected %v, got %v", want, reasons)
+		}
+	})
+
+	t.Run("Test After", func(t *testing.T) {
+		m := newMachine(t, endlessFrames{frame(black)},
+			statemachine.State{
+				Name:        "waiting",
+				Transitions: []statemachine.Transition{{To: "done", After: 30 * time.Millisecond}},
+			},
+			statemachine.State{Name: "done", Final: true},
+		)
+		m.SetInitial("waiting")
+
+		start := time.Now()
+		if err := m.Run(context.Background()); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+			t.Errorf("expected to wait at least %v, waited %v", 30*time.Millisecond, elapsed)
+		}
+	})
+
+	t.Run("Test Timeout", func(t *testing.T) {
+		m := newMachine(t, endlessFrames{frame(black)},
+			statemachine.State{Name: "stuck", Timeout: 20 * time.Millisecond},
+		)
+		m.SetInitial("stuck")
+
+		if err := m.Run(context.Background()); !errors.Is(err, &statemachine.StateTimeoutError{}) {
+			t.Errorf("expected %v, got %v", &statemachine.StateTimeoutError{}, err)
+		}
+	})
+
+	t.Run("Test errors", func(t *testing.T) {
+		actionErr := errors.New("action failed")
+		m := newMachine(t, endlessFrames{frame(red)},
+			statemachine.State{
+				Name:      "menu",
+				Detectors: []statemachine.Detector{shows(red)},
+				Actions: []statemachine.Action{func(ctx context.Context, t *statemachine.Tick) error {
+					return actionErr
+				}},
+			},
+		)
+
+		if err := m.AddState(statemachine.State{Name: "menu"}); !errors.Is(err, &statemachine.DuplicateStateError{}) {
+			t.Errorf("expected %v, got %v", &statemachine.DuplicateStateError{}, err)
+		}
+		if err := m.Run(context.Background()); !errors.Is(err, actionErr) {
+			t.Errorf("expected %v, got %v", actionErr, err)
+		}
+
+		m.SetInitial("missing")
+		if err := m.Run(context.Background()); !errors.Is(err, &statemachine.UnknownStateError{}) {
+			t.Errorf("expected %v, got %v", &statemachine.UnknownStateError{}, err)
+		}
+	})
+
+	t.Run("Test unknown state added while running", func(t *testing.T) {
+		var m *statemachine.Machine
+		m = newMachine(t, endlessFrames{frame(black)},
+			statemachine.State{
+				Name: "idle",
+				OnEnter: func(ctx context.Context, t *statemachine.Tick) error {
+					return m.AddState(statemachine.State{
+						Name:        "next",
+						Detectors:   []statemachine.Detector{shows(black)},
+						Transitions: []statemachine.Transition{{To: "typo"}},
+					})
+				},
+			},
+		)
+		m.SetInitial("idle")
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		if err := m.Run(ctx); !errors.Is(err, &statemachine.UnknownStateError{}) {
+			t.Errorf("expected %v, got %v", &statemachine.UnknownStateError{}, err)
+		}
+		if m.Current() != "next" {
+			t.Errorf("expected %v, got %v", "next", m.Current())
+		}
+	})
+
+	t.Run("Test cancellation", func(t *testing.T) {
+		m := newMachine(t, endlessFrames{frame(black)})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		if err := m.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+		}
+	})
+}
+
+func TestPixelDetector(t *testing.T) {
+	d := statemachine.PixelDetector(
+		statemachine.Pixel{Point: image.Pt(0, 0), Color: gamebot.ColorRange{Min: color.RGBA{R: 200}, Max: color.RGBA{R: 255, G: 50, B: 50}}},
+	)
+
+	tests := []struct {
+		name  string
+		frame *image.Image
+		want  bool
+	}{
+		{"Test matching pixel", frame(red), true},
+		{"Test different pixel", frame(blue), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.Detect(context.Background(), tt.frame)
+			if err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	outside := statemachine.PixelDetector(statemachine.Pixel{Point: image.Pt(20, 20), Color: gamebot.ColorRange{Max: color.RGBA{R: 255, G: 255, B: 255}}})
+	if got, _ := outside.Detect(context.Background(), frame(red)); got {
+		t.Errorf("expected a pixel outside the frame not to match")
+	}
+}
@@ -0,0 +1,215 @@
+package gamebot
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gocv.io/x/gocv"
+)
+
+// templateThreshold represents the default score a match must pass for a template to be found.
+const templateThreshold = 0.8
+
+// Template is an image to search for within the bot's window along with the information needed to search for it.
+//
+// The template's pixels are converted for opencv once, when the template is created, so a template can be matched
+// many times without converting it again. A Template holds native memory and must be closed once it is no longer needed.
+type Template struct {
+	// Name identifies the template in matches, debug output and logs.
+	Name string
+	// Path is the file the template was loaded from, if any.
+	Path string
+	// Threshold is the score a match must pass for the template to be found using the bot's opencv template matching
+	// mode. For the Sqdiff modes the score must be less than or equal to the threshold, for every other mode it must
+	// be greater than or equal to it. Defaults to 0.8.
+	Threshold float32
+	// Region is the area of the window to search for the template. If it is empty, or too small to hold the
+	// template, the whole window is searched.
+	Region image.Rectangle
+
+	mut    sync.RWMutex
+	img    image.Image
+	mat    gocv.Mat
+	mask   gocv.Mat
+	closed bool
+}
+
+// NewTemplate creates a template called `name` from `img`. If `img` has transparent pixels they are masked
+// so they do not affect the match score.
+func NewTemplate(name string, img image.Image) (*Template, error) {
+	mat, err := gocv.ImageToMatRGB(img)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert img to gocv.Mat: %v", err)
+	}
+
+	t := &Template{
+		Name:      name,
+		Threshold: templateThreshold,
+		img:       img,
+		mat:       mat,
+		mask:      gocv.NewMat(),
+	}
+
+	if hasTransparency(img) {
+		if err := t.SetMask(alphaMask(img)); err != nil {
+			t.Close()
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// NewTemplateFromBytes creates a template called `name` from encoded image data, e.g. an image embedded with go:embed.
+func NewTemplateFromBytes(name string, data []byte) (*Template, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode template %s: %v", name, err)
+	}
+
+	return NewTemplate(name, img)
+}
+
+// LoadTemplate creates a template from the image file at `path`. The template is named after the file without its extension.
+func LoadTemplate(path string) (*Template, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode template %s: %v", path, err)
+	}
+
+	t, err := NewTemplate(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), img)
+	if err != nil {
+		return nil, err
+	}
+	t.Path = path
+
+	return t, nil
+}
+
+// LoadTemplateFS creates a template from the image file at `name` within `fsys`, e.g. an embed.FS.
+// The template is named after the file without its extension.
+func LoadTemplateFS(fsys fs.FS, name string) (*Template, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode template %s: %v", name, err)
+	}
+
+	t, err := NewTemplate(strings.TrimSuffix(path.Base(name), path.Ext(name)), img)
+	if err != nil {
+		return nil, err
+	}
+	t.Path = name
+
+	return t, nil
+}
+
+// (t *Template) Image returns the template's image.
+func (t *Template) Image() image.Image {
+	return t.img
+}
+
+// (t *Template) Size returns the width and height of the template.
+func (t *Template) Size() image.Point {
+	return t.img.Bounds().Size()
+}
+
+// (t *Template) SetMask sets the pixels of the template that are compared when matching. Black pixels of `mask`
+// are ignored and every other pixel is compared. The mask must be the same size as the template.
+func (t *Template) SetMask(mask image.Image) error {
+	if mask.Bounds().Size() != t.Size() {
+		return fmt.Errorf("mask size %v does not match template size %v", mask.Bounds().Size(), t.Size())
+	}
+
+	binary := image.NewRGBA(image.Rect(0, 0, mask.Bounds().Dx(), mask.Bounds().Dy()))
+	for y := 0; y < binary.Bounds().Dy(); y++ {
+		for x := 0; x < binary.Bounds().Dx(); x++ {
+			r, g, b, _ := mask.At(mask.Bounds().Min.X+x, mask.Bounds().Min.Y+y).RGBA()
+			if r|g|b != 0 {
+				binary.Set(x, y, color.White)
+			} else {
+				binary.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	mat, err := gocv.ImageToMatRGB(binary)
+	if err != nil {
+		return fmt.Errorf("failed to convert mask to gocv.Mat: %v", err)
+	}
+
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	t.mask.Close()
+	t.mask = mat
+	return nil
+}
+
+// (t *Template) Close frees the native memory held by the template. A closed template can no longer be matched.
+func (t *Template) Close() error {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if t.closed {
+		return nil
+	}
+
+	t.closed = true
+	t.mat.Close()
+	t.mask.Close()
+
+	return nil
+}
+
+// hasTransparency returns true if any pixel of `img` is not fully opaque.
+func hasTransparency(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return !o.Opaque()
+	}
+
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0xffff {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// alphaMask returns an image that is white where `img` is visible and black where it is fully transparent.
+func alphaMask(img image.Image) image.Image {
+	b := img.Bounds()
+	mask := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			if _, _, _, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA(); a != 0 {
+				mask.SetGray(x, y, color.Gray{255})
+			}
+		}
+	}
+
+	return mask
+}
@@ -0,0 +1,132 @@
+package gamebot
+
+import (
+	"image"
+	"math"
+)
+
+// CoordinateTransform maps points between the three coordinate spaces a bot works with:
+//
+//	capture space  pixels of a screen capture, measured from the top-left corner of the screen
+//	logical space  the units the operating system uses to position the cursor and windows
+//	window space   pixels of a window capture, measured from the top-left corner of the window
+//
+// On displays without scaling capture space and logical space are the same. On scaled displays, e.g. a 4K
+// monitor at 200%, one logical unit covers several captured pixels.
+type CoordinateTransform struct {
+	// Scale is the number of captured pixels per logical unit.
+	Scale float64
+	// WindowOrigin is the top-left corner of the window in logical space.
+	WindowOrigin image.Point
+}
+
+// (t CoordinateTransform) CaptureToLogical converts a point in capture space to logical space.
+func (t CoordinateTransform) CaptureToLogical(p image.Point) image.Point {
+	return scalePoint(p, 1/t.scale())
+}
+
+// (t CoordinateTransform) LogicalToCapture converts a point in logical space to capture space.
+func (t CoordinateTransform) LogicalToCapture(p image.Point) image.Point {
+	return scalePoint(p, t.scale())
+}
+
+// (t CoordinateTransform) WindowToCapture converts a point in window space to capture space.
+func (t CoordinateTransform) WindowToCapture(p image.Point) image.Point {
+	return t.LogicalToCapture(t.WindowOrigin).Add(p)
+}
+
+// (t CoordinateTransform) CaptureToWindow converts a point in capture space to window space.
+func (t CoordinateTransform) CaptureToWindow(p image.Point) image.Point {
+	return p.Sub(t.LogicalToCapture(t.WindowOrigin))
+}
+
+// (t CoordinateTransform) WindowToLogical converts a point in window space to logical space.
+func (t CoordinateTransform) WindowToLogical(p image.Point) image.Point {
+	return t.CaptureToLogical(t.WindowToCapture(p))
+}
+
+// (t CoordinateTransform) LogicalToWindow converts a point in logical space to window space.
+func (t CoordinateTransform) LogicalToWindow(p image.Point) image.Point {
+	return t.CaptureToWindow(t.LogicalToCapture(p))
+}
+
+// scale returns the transform's scale, treating an unset scale as 1.
+func (t CoordinateTransform) scale() float64 {
+	if t.Scale <= 0 {
+		return 1
+	}
+	return t.Scale
+}
+
+// scalePoint multiplies both coordinates of `p` by `f`, rounding to the nearest pixel.
+func scalePoint(p image.Point, f float64) image.Point {
+	return image.Pt(int(math.Round(float64(p.X)*f)), int(math.Round(float64(p.Y)*f)))
+}
+
+// (b *Bot) Transform returns the transform between the capture, logical and window coordinate spaces for the bot's
+// current window and display scale.
+func (b *Bot) Transform() CoordinateTransform {
+	b.config.botRWMut.RLock()
+	defer b.config.botRWMut.RUnlock()
+
+	return CoordinateTransform{
+		Scale:        b.config.displayScale,
+		WindowOrigin: image.Pt(b.config.window.position.x, b.config.window.position.y),
+	}
+}
+
+// (b *Bot) DisplayScale returns the number of captured pixels per logical screen unit.
+func (b *Bot) DisplayScale() float64 {
+	b.config.botRWMut.RLock()
+	defer b.config.botRWMut.RUnlock()
+
+	return b.config.displayScale
+}
+
+// (b *Bot) SetDisplayScale overrides the display scale detected when the bot was created. This is useful when the
+// detected scale is wrong for the game or for testing. A scale less than or equal to 0 is treated as 1.
+func (b *Bot) SetDisplayScale(scale float64) {
+	b.config.botRWMut.Lock()
+	defer b.config.botRWMut.Unlock()
+
+	if scale <= 0 {
+		scale = 1
+	}
+	b.config.displayScale = scale
+}
+
+// (b *Bot) WindowToScreen converts a point in the bot's window, e.g. the location of a Match, to a point on the screen
+// that can be passed to the bot's mouse functions.
+func (b *Bot) WindowToScreen(p image.Point) image.Point {
+	return b.Transform().WindowToCapture(p)
+}
+
+// (b *Bot) WindowBounds returns the area of the screen covered by the bot's window.
+func (b *Bot) WindowBounds() image.Rectangle {
+	b.config.botRWMut.RLock()
+	w := b.config.window.size
+	b.config.botRWMut.RUnlock()
+
+	t := b.Transform()
+	return image.Rectangle{
+		Min: t.WindowToCapture(image.Pt(0, 0)),
+		Max: t.WindowToCapture(t.LogicalToCapture(image.Pt(w.w, w.h))),
+	}
+}
+
+// (b *Bot) ScreenToWindow converts a point on the screen, e.g. the position of the cursor, to a point in the bot's window.
+func (b *Bot) ScreenToWindow(p image.Point) image.Point {
+	return b.Transform().CaptureToWindow(p)
+}
+
+// toLogical converts a screen point given to one of the bot's mouse functions to the units the operating system uses.
+func (b *Bot) toLogical(x, y int) (int, int) {
+	p := b.Transform().CaptureToLogical(image.Pt(x, y))
+	return p.X, p.Y
+}
+
+// fromLogical converts a point from the units the operating system uses to a screen point returned by the bot's mouse functions.
+func (b *Bot) fromLogical(x, y int) (int, int) {
+	p := b.Transform().LogicalToCapture(image.Pt(x, y))
+	return p.X, p.Y
+}
@@ -0,0 +1,198 @@
+package gamebot
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DurationRange is a range of durations that random durations are drawn from.
+// Durations are drawn from a normal distribution so they favour the middle of the range.
+type DurationRange struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// draw returns a random duration within the range.
+func (d DurationRange) draw(r *rand.Rand) time.Duration {
+	if d.Max <= d.Min {
+		return d.Min
+	}
+
+	// Three standard deviations either side of the middle reaches the ends of the range.
+	mid := float64(d.Min+d.Max) / 2
+	sd := float64(d.Max-d.Min) / 6
+	v := time.Duration(mid + r.NormFloat64()*sd)
+
+	if v < d.Min {
+		return d.Min
+	}
+	if v > d.Max {
+		return d.Max
+	}
+	return v
+}
+
+// KeyTiming controls how long the bot holds keys and how long it waits between them.
+type KeyTiming struct {
+	// Hold is how long each key is held down.
+	Hold DurationRange
+	// Gap is how long TypeText waits between characters.
+	Gap DurationRange
+	// TypoRate is the chance, between 0 and 1, that TypeText presses a neighbouring key by mistake for a character
+	// and corrects it with backspace. Typos are disabled when it is 0.
+	TypoRate float64
+}
+
+// DefaultKeyTiming returns the key timing bots use until `(b *Bot) SetKeyTiming` is called.
+func DefaultKeyTiming() KeyTiming {
+	return KeyTiming{
+		Hold: DurationRange{Min: 50 * time.Millisecond, Max: 130 * time.Millisecond},
+		Gap:  DurationRange{Min: 40 * time.Millisecond, Max: 200 * time.Millisecond},
+	}
+}
+
+// (b *Bot) KeyTiming returns the bot's key timing.
+func (b *Bot) KeyTiming() KeyTiming {
+	b.config.botRWMut.RLock()
+	defer b.config.botRWMut.RUnlock()
+
+	return b.config.keyTiming
+}
+
+// (b *Bot) SetKeyTiming sets how long the bot holds keys and how long it waits between them when typing.
+func (b *Bot) SetKeyTiming(timing KeyTiming) {
+	b.config.botRWMut.Lock()
+	defer b.config.botRWMut.Unlock()
+
+	b.config.keyTiming = timing
+}
+
+// (b *Bot) TypeText types `s` one character at a time using the bot's key timing. Uppercase letters and symbols are
+// typed by holding shift, assuming a US keyboard layout. Characters that cannot be typed with a single key, such as
+// accented letters, are typed through the operating system's unicode input.
+//
+// If `ctx` is cancelled typing stops, no keys are left in a down state and the context's error is returned. Typing waits
+// for any queued action that is running, see `(b *Bot) Submit`, and queued actions wait for the typing to finish.
+func (b *Bot) TypeText(ctx context.Context, s string) error {
+	b.config.executor.running.Lock()
+	defer b.config.executor.running.Unlock()
+
+	timing := b.KeyTiming()
+
+	for _, c := range s {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		keys, ok := charKeys(c)
+		if !ok {
+			if err := b.checkInput(); err != nil {
+				return err
+			}
+			if err := b.guardFocus(); err != nil {
+				return err
+			}
+			if err := b.limitInput(ctx, keyAction, string(c)); err != nil {
+				return err
+			}
+			if err := b.input().Type(string(c)); err != nil {
+				return err
+			}
+		} else {
+			if timing.TypoRate > 0 && b.rand().Float64() < timing.TypoRate {
+				if err := b.typo(ctx, keys, timing); err != nil {
+					return err
+				}
+			}
+			if err := b.tapKeys(ctx, keys, timing.Hold); err != nil {
+				return err
+			}
+		}
+
+		if err := sleepContext(ctx, timing.Gap.draw(b.rand())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// typo types a key next to the last of `keys`, pauses as if noticing the mistake, then deletes it with backspace.
+func (b *Bot) typo(ctx context.Context, keys []Key, timing KeyTiming) error {
+	neighbours := keyNeighbours(keys[len(keys)-1])
+	if len(neighbours) == 0 {
+		return nil
+	}
+
+	wrong := append(append([]Key{}, keys[:len(keys)-1]...), neighbours[b.rand().Intn(len(neighbours))])
+	if err := b.tapKeys(ctx, wrong, timing.Hold); err != nil {
+		return err
+	}
+
+	// Noticing a typo takes longer than moving on to the next key.
+	if err := sleepContext(ctx, 2*timing.Gap.draw(b.rand())); err != nil {
+		return err
+	}
+	if err := b.tapKeys(ctx, []Key{KeyBackspace}, timing.Hold); err != nil {
+		return err
+	}
+
+	return sleepContext(ctx, timing.Gap.draw(b.rand()))
+}
+
+// shiftedKeys maps the symbols typed while holding shift to the key that types them on a US keyboard.
+var shiftedKeys = map[rune]string{
+	'~': "`", '!': "1", '@': "2", '#': "3", '$': "4", '%': "5", '^': "6", '&': "7", '*': "8", '(': "9", ')': "0",
+	'_': "-", '+': "=", '{': "[", '}': "]", '|': "\\", ':': ";", '"': "'", '<': ",", '>': ".", '?': "/",
+}
+
+// keyboardRows are the rows of a US keyboard, used to find the keys next to a key.
+var keyboardRows = []string{"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./"}
+
+// charKeys returns the keys pressed to type `c`, or false if `c` cannot be typed with a single key.
+func charKeys(c rune) ([]Key, bool) {
+	switch c {
+	case ' ':
+		return []Key{KeySpace}, true
+	case '\n':
+		return []Key{KeyEnter}, true
+	case '\t':
+		return []Key{KeyTab}, true
+	}
+
+	if key, ok := shiftedKeys[c]; ok {
+		return []Key{KeyShift, Key(key)}, true
+	}
+	if c >= 'A' && c <= 'Z' {
+		return []Key{KeyShift, Key(string(unicode.ToLower(c)))}, true
+	}
+	for _, row := range keyboardRows {
+		if strings.ContainsRune(row, c) {
+			return []Key{Key(string(c))}, true
+		}
+	}
+
+	return nil, false
+}
+
+// keyNeighbours returns the keys either side of `key` on a US keyboard.
+func keyNeighbours(key Key) []Key {
+	var neighbours []Key
+	for _, row := range keyboardRows {
+		i := strings.Index(row, string(key))
+		if len(key) != 1 || i < 0 {
+			continue
+		}
+		if i > 0 {
+			neighbours = append(neighbours, Key(row[i-1:i]))
+		}
+		if i < len(row)-1 {
+			neighbours = append(neighbours, Key(row[i+1:i+2]))
+		}
+	}
+
+	return neighbours
+}
@@ -0,0 +1,495 @@
+package gamebot
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/go-vgo/robotgo"
+)
+
+// Linux input event types and codes, see linux/input-event-codes.h.
+const (
+	evSyn = 0x00
+	evKey = 0x01
+	evRel = 0x02
+	evAbs = 0x03
+
+	synReport = 0x00
+
+	relX      = 0x00
+	relY      = 0x01
+	relHWheel = 0x06
+	relWheel  = 0x08
+
+	absX     = 0x00
+	absY     = 0x01
+	absZ     = 0x02
+	absRX    = 0x03
+	absRY    = 0x04
+	absRZ    = 0x05
+	absHat0X = 0x10
+	absHat0Y = 0x11
+
+	btnLeft   = 0x110
+	btnRight  = 0x111
+	btnMiddle = 0x112
+)
+
+const (
+	// uinputTimevalSize is the size of the timestamp that starts each event, two C longs.
+	uinputTimevalSize = 2 * strconv.IntSize / 8
+	// uinputEventSize is the size of a struct input_event.
+	uinputEventSize = uinputTimevalSize + 8
+
+	// uinputStickMin and uinputStickMax are the range of a gamepad stick's axes.
+	uinputStickMin = -32768
+	uinputStickMax = 32767
+	// uinputTriggerMax is the value of a fully pulled gamepad trigger.
+	uinputTriggerMax = 255
+)
+
+// uinputEvent is a single event written to a uinput device.
+type uinputEvent struct {
+	typ   uint16
+	code  uint16
+	value int32
+}
+
+// appendTo appends the encoding of `e` as a struct input_event to `buf`. The timestamp is left as zero for the
+// kernel to fill in. Events are encoded little endian, as on every platform the bot runs on.
+func (e uinputEvent) appendTo(buf []byte) []byte {
+	var ev [uinputEventSize]byte
+	binary.LittleEndian.PutUint16(ev[uinputTimevalSize:], e.typ)
+	binary.LittleEndian.PutUint16(ev[uinputTimevalSize+2:], e.code)
+	binary.LittleEndian.PutUint32(ev[uinputTimevalSize+4:], uint32(e.value))
+	return append(buf, ev[:]...)
+}
+
+// uinputKeys maps the bot's key names to Linux key codes.
+var uinputKeys = map[Key]uint16{
+	KeyEscape: 1, "1": 2, "2": 3, "3": 4, "4": 5, "5": 6, "6": 7, "7": 8, "8": 9, "9": 10, "0": 11, "-": 12, "=": 13,
+	KeyBackspace: 14, KeyTab: 15,
+	"q": 16, "w": 17, "e": 18, "r": 19, "t": 20, "y": 21, "u": 22, "i": 23, "o": 24, "p": 25, "[": 26, "]": 27,
+	KeyEnter: 28, KeyCtrl: 29, KeyLCtrl: 29,
+	"a": 30, "s": 31, "d": 32, "f": 33, "g": 34, "h": 35, "j": 36, "k": 37, "l": 38, ";": 39, "'": 40, "`": 41,
+	KeyShift: 42, KeyLShift: 42, "\\": 43,
+	"z": 44, "x": 45, "c": 46, "v": 47, "b": 48, "n": 49, "m": 50, ",": 51, ".": 52, "/": 53,
+	KeyRShift: 54, KeyAlt: 56, KeyLAlt: 56, KeySpace: 57, KeyCapsLock: 58,
+	KeyF1: 59, KeyF2: 60, KeyF3: 61, KeyF4: 62, KeyF5: 63, KeyF6: 64, KeyF7: 65, KeyF8: 66, KeyF9: 67, KeyF10: 68,
+	KeyNumLock: 69, KeyNum7: 71, KeyNum8: 72, KeyNum9: 73, KeyNum4: 75, KeyNum5: 76, KeyNum6: 77,
+	KeyNum1: 79, KeyNum2: 80, KeyNum3: 81, KeyNum0: 82, KeyF11: 87, KeyF12: 88,
+	KeyRCtrl: 97, KeyPrintScreen: 99, KeyRAlt: 100,
+	KeyHome: 102, KeyUp: 103, KeyPageUp: 104, KeyLeft: 105, KeyRight: 106, KeyEnd: 107, KeyDown: 108,
+	KeyPageDown: 109, KeyInsert: 110, KeyDelete: 111,
+	"audio_mute": 113, "audio_vol_down": 114, "audio_vol_up": 115,
+	KeyCmd: 125, KeyLCmd: 125, KeyRCmd: 126, KeyMenu: 127,
+	"audio_next": 163, "audio_play": 164, "audio_prev": 165, "audio_stop": 166, "audio_pause": 201,
+	"f13": 183, "f14": 184, "f15": 185, "f16": 186, "f17": 187, "f18": 188,
+	"f19": 189, "f20": 190, "f21": 191, "f22": 192, "f23": 193, "f24": 194,
+}
+
+// uinputMouseButtons maps the bot's mouse buttons to Linux button codes.
+var uinputMouseButtons = map[MouseButton]uint16{
+	Left:   btnLeft,
+	Right:  btnRight,
+	Center: btnMiddle,
+}
+
+// uinputGamepadButtons maps gamepad buttons to Linux button codes. The d-pad is reported as a hat instead, like the
+// Xbox controller driver does.
+var uinputGamepadButtons = map[GamepadButton]uint16{
+	GamepadA:          0x130,
+	GamepadB:          0x131,
+	GamepadX:          0x133,
+	GamepadY:          0x134,
+	GamepadLB:         0x136,
+	GamepadRB:         0x137,
+	GamepadBack:       0x13a,
+	GamepadStart:      0x13b,
+	GamepadGuide:      0x13c,
+	GamepadLeftThumb:  0x13d,
+	GamepadRightThumb: 0x13e,
+}
+
+// uinputDPad maps the d-pad's buttons to the hat axis and value they set.
+var uinputDPad = map[GamepadButton]struct {
+	axis  uint16
+	value int32
+}{
+	GamepadDPadUp:    {absHat0Y, -1},
+	GamepadDPadDown:  {absHat0Y, 1},
+	GamepadDPadLeft:  {absHat0X, -1},
+	GamepadDPadRight: {absHat0X, 1},
+}
+
+// uinputAbs is the range of an absolute axis of a uinput device.
+type uinputAbs struct {
+	code     uint16
+	min, max int32
+	flat     int32
+}
+
+// uinputSpec describes a uinput device to create.
+type uinputSpec struct {
+	name            string
+	vendor, product uint16
+	keys            []uint16
+	rels            []uint16
+	abs             []uinputAbs
+}
+
+// UinputOptions configure the devices created by NewUinputDriver.
+type UinputOptions struct {
+	// RelativeMouse moves the cursor with relative motion, like a real mouse, instead of jumping to absolute positions.
+	// Games that capture the mouse, e.g. first person games, often only see relative motion. Relative motion is
+	// subject to the desktop's pointer acceleration, so it should be turned off for moves to be accurate.
+	RelativeMouse bool
+	// ScreenWidth and ScreenHeight are the size of the screen in the operating system's units, the range the
+	// absolute mouse is positioned in. If either is 0 the size of the main screen is used.
+	ScreenWidth, ScreenHeight int
+	// Gamepad creates a virtual gamepad that is seen by games as an Xbox controller.
+	Gamepad bool
+	// CursorPosition returns the position of the cursor, which a uinput device cannot read. If it is nil the position
+	// is read with robotgo.
+	CursorPosition func() (int, int)
+}
+
+// UinputDevices are the devices a UinputDriver writes events to. Each write is one or more encoded struct
+// input_event. Devices created with NewUinputDriver write to /dev/uinput, tests can use a bytes.Buffer instead.
+// A nil Gamepad means the driver has no gamepad.
+type UinputDevices struct {
+	Keyboard io.Writer
+	Mouse    io.Writer
+	Gamepad  io.Writer
+}
+
+// UinputDriver is an InputDriver, and a GamepadDriver if it has a gamepad, that sends input through virtual devices
+// created with the Linux uinput module. Games see the input as coming from real hardware, unlike the XTest input
+// robotgo sends, which some games ignore. Use `(b *Bot) SetInputDriver` to send the bot's input through it.
+type UinputDriver struct {
+	mut sync.Mutex
+
+	devices UinputDevices
+	opts    UinputOptions
+	// hat is the value of each of the gamepad's hat axes.
+	hat map[uint16]int32
+}
+
+// NewUinputDriverFromDevices creates a UinputDriver that writes to `devices`, e.g. to test a bot's input without
+// /dev/uinput. Use NewUinputDriver to create real devices.
+func NewUinputDriverFromDevices(devices UinputDevices, opts UinputOptions) *UinputDriver {
+	if opts.CursorPosition == nil {
+		opts.CursorPosition = robotgo.GetMousePos
+	}
+
+	return &UinputDriver{
+		devices: devices,
+		opts:    opts,
+		hat:     make(map[uint16]int32),
+	}
+}
+
+// (d *UinputDriver) Close removes the driver's devices. The bot should be closed first so no keys are left pressed.
+func (d *UinputDriver) Close() error {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	var firstErr error
+	for _, w := range []io.Writer{d.devices.Keyboard, d.devices.Mouse, d.devices.Gamepad} {
+		if c, ok := w.(io.Closer); ok {
+			if err := c.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// write sends `events` to the device `w` followed by a report, so the device applies them at once.
+func (d *UinputDriver) write(w io.Writer, events ...uinputEvent) error {
+	if w == nil {
+		return fmt.Errorf("uinput device has not been created")
+	}
+
+	buf := make([]byte, 0, (len(events)+1)*uinputEventSize)
+	for _, e := range events {
+		buf = e.appendTo(buf)
+	}
+	buf = uinputEvent{typ: evSyn, code: synReport}.appendTo(buf)
+
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	_, err := w.Write(buf)
+	return err
+}
+
+// keyCodes returns the codes of the keys pressed to press `key`, e.g. shift and a for "A".
+func keyCodes(key string) ([]uint16, error) {
+	if code, ok := uinputKeys[Key(key)]; ok {
+		return []uint16{code}, nil
+	}
+
+	if r := []rune(key); len(r) == 1 {
+		if keys, ok := charKeys(r[0]); ok {
+			codes := make([]uint16, len(keys))
+			for i, k := range keys {
+				codes[i] = uinputKeys[k]
+			}
+			return codes, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s cannot be pressed with a uinput keyboard", key)
+}
+
+func (d *UinputDriver) KeyDown(key string) error {
+	codes, err := keyCodes(key)
+	if err != nil {
+		return err
+	}
+
+	events := make([]uinputEvent, len(codes))
+	for i, code := range codes {
+		events[i] = uinputEvent{typ: evKey, code: code, value: 1}
+	}
+	return d.write(d.devices.Keyboard, events...)
+}
+
+func (d *UinputDriver) KeyUp(key string) error {
+	codes, err := keyCodes(key)
+	if err != nil {
+		return err
+	}
+
+	// Release in reverse order, so shift is released after the key it modifies.
+	events := make([]uinputEvent, len(codes))
+	for i, code := range codes {
+		events[len(codes)-1-i] = uinputEvent{typ: evKey, code: code, value: 0}
+	}
+	return d.write(d.devices.Keyboard, events...)
+}
+
+func (d *UinputDriver) MouseDown(btn MouseButton) error {
+	return d.mouseButton(btn, 1)
+}
+
+func (d *UinputDriver) MouseUp(btn MouseButton) error {
+	return d.mouseButton(btn, 0)
+}
+
+// mouseButton sets `btn` to `value`, 1 for down and 0 for up.
+func (d *UinputDriver) mouseButton(btn MouseButton, value int32) error {
+	code, ok := uinputMouseButtons[btn]
+	if !ok {
+		return fmt.Errorf("mouse button %s cannot be pressed with a uinput mouse", btn)
+	}
+	return d.write(d.devices.Mouse, uinputEvent{typ: evKey, code: code, value: value})
+}
+
+func (d *UinputDriver) Move(x, y int) error {
+	if !d.opts.RelativeMouse {
+		return d.write(d.devices.Mouse,
+			uinputEvent{typ: evAbs, code: absX, value: int32(x)},
+			uinputEvent{typ: evAbs, code: absY, value: int32(y)},
+		)
+	}
+
+	cx, cy := d.opts.CursorPosition()
+	if x == cx && y == cy {
+		return nil
+	}
+	return d.write(d.devices.Mouse,
+		uinputEvent{typ: evRel, code: relX, value: int32(x - cx)},
+		uinputEvent{typ: evRel, code: relY, value: int32(y - cy)},
+	)
+}
+
+func (d *UinputDriver) MousePosition() (int, int) {
+	return d.opts.CursorPosition()
+}
+
+func (d *UinputDriver) Scroll(x, y int) error {
+	// A positive wheel value scrolls up and a positive horizontal wheel value scrolls right.
+	var events []uinputEvent
+	if y != 0 {
+		events = append(events, uinputEvent{typ: evRel, code: relWheel, value: int32(y)})
+	}
+	if x != 0 {
+		events = append(events, uinputEvent{typ: evRel, code: relHWheel, value: int32(-x)})
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	return d.write(d.devices.Mouse, events...)
+}
+
+// (d *UinputDriver) Type types `s` by tapping the key of each character. Only characters on a US keyboard can be typed,
+// as a uinput keyboard has no unicode input.
+func (d *UinputDriver) Type(s string) error {
+	for _, c := range s {
+		if _, ok := charKeys(c); !ok {
+			return fmt.Errorf("%q cannot be typed with a uinput keyboard", c)
+		}
+		if err := d.KeyDown(string(c)); err != nil {
+			return err
+		}
+		if err := d.KeyUp(string(c)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *UinputDriver) HasGamepad() bool {
+	return d.devices.Gamepad != nil
+}
+
+func (d *UinputDriver) ButtonDown(btn GamepadButton) error {
+	return d.gamepadButton(btn, true)
+}
+
+func (d *UinputDriver) ButtonUp(btn GamepadButton) error {
+	return d.gamepadButton(btn, false)
+}
+
+// gamepadButton puts `btn` in a down state if `down` is true, otherwise in an up state.
+func (d *UinputDriver) gamepadButton(btn GamepadButton, down bool) error {
+	if code, ok := uinputGamepadButtons[btn]; ok {
+		value := int32(0)
+		if down {
+			value = 1
+		}
+		return d.write(d.devices.Gamepad, uinputEvent{typ: evKey, code: code, value: value})
+	}
+
+	dpad, ok := uinputDPad[btn]
+	if !ok {
+		return fmt.Errorf("gamepad button %s cannot be pressed with a uinput gamepad", btn)
+	}
+
+	d.mut.Lock()
+	value := d.hat[dpad.axis]
+	switch {
+	case down:
+		value = dpad.value
+	case value == dpad.value:
+		// Releasing a direction centers the hat, unless the opposite direction has been pressed since.
+		value = 0
+	}
+	d.hat[dpad.axis] = value
+	d.mut.Unlock()
+
+	return d.write(d.devices.Gamepad, uinputEvent{typ: evAbs, code: dpad.axis, value: value})
+}
+
+func (d *UinputDriver) SetStick(stick GamepadStick, x, y float64) error {
+	xAxis, yAxis := uint16(absX), uint16(absY)
+	switch stick {
+	case LeftStick:
+	case RightStick:
+		xAxis, yAxis = absRX, absRY
+	default:
+		return fmt.Errorf("%s is not a gamepad stick", stick)
+	}
+
+	return d.write(d.devices.Gamepad,
+		uinputEvent{typ: evAbs, code: xAxis, value: stickValue(x)},
+		uinputEvent{typ: evAbs, code: yAxis, value: stickValue(y)},
+	)
+}
+
+func (d *UinputDriver) SetTrigger(trigger GamepadTrigger, value float64) error {
+	axis := uint16(absZ)
+	switch trigger {
+	case LeftTrigger:
+	case RightTrigger:
+		axis = absRZ
+	default:
+		return fmt.Errorf("%s is not a gamepad trigger", trigger)
+	}
+
+	v := int32(math.Round(clampFloat(value, 0, 1) * uinputTriggerMax))
+	return d.write(d.devices.Gamepad, uinputEvent{typ: evAbs, code: axis, value: v})
+}
+
+// stickValue converts a stick position between -1 and 1 to the value of a stick's axis.
+func stickValue(v float64) int32 {
+	v = clampFloat(v, -1, 1)
+	if v < 0 {
+		return int32(math.Round(v * -uinputStickMin))
+	}
+	return int32(math.Round(v * uinputStickMax))
+}
+
+// keyboardSpec describes the virtual keyboard, which has every key the bot can press.
+func keyboardSpec() uinputSpec {
+	seen := make(map[uint16]bool)
+	var keys []uint16
+	for _, code := range uinputKeys {
+		if !seen[code] {
+			seen[code] = true
+			keys = append(keys, code)
+		}
+	}
+
+	return uinputSpec{
+		name:    "gamebot virtual keyboard",
+		vendor:  0x1209,
+		product: 0x0001,
+		keys:    keys,
+	}
+}
+
+// mouseSpec describes the virtual mouse. An absolute mouse is reported like a virtual machine's tablet, which desktops
+// treat as a mouse that jumps to a position.
+func mouseSpec(opts UinputOptions) uinputSpec {
+	spec := uinputSpec{
+		name:    "gamebot virtual mouse",
+		vendor:  0x1209,
+		product: 0x0002,
+		keys:    []uint16{btnLeft, btnRight, btnMiddle},
+		rels:    []uint16{relWheel, relHWheel},
+	}
+
+	if opts.RelativeMouse {
+		spec.rels = append(spec.rels, relX, relY)
+	} else {
+		spec.abs = []uinputAbs{
+			{code: absX, max: int32(opts.ScreenWidth - 1)},
+			{code: absY, max: int32(opts.ScreenHeight - 1)},
+		}
+	}
+
+	return spec
+}
+
+// gamepadSpec describes the virtual gamepad. It uses the ids of an Xbox 360 controller so games know its layout.
+func gamepadSpec() uinputSpec {
+	spec := uinputSpec{
+		name:    "gamebot virtual gamepad",
+		vendor:  0x045e,
+		product: 0x028e,
+		abs: []uinputAbs{
+			{code: absX, min: uinputStickMin, max: uinputStickMax, flat: 128},
+			{code: absY, min: uinputStickMin, max: uinputStickMax, flat: 128},
+			{code: absRX, min: uinputStickMin, max: uinputStickMax, flat: 128},
+			{code: absRY, min: uinputStickMin, max: uinputStickMax, flat: 128},
+			{code: absZ, max: uinputTriggerMax},
+			{code: absRZ, max: uinputTriggerMax},
+			{code: absHat0X, min: -1, max: 1},
+			{code: absHat0Y, min: -1, max: 1},
+		},
+	}
+	for _, code := range uinputGamepadButtons {
+		spec.keys = append(spec.keys, code)
+	}
+
+	return spec
+}
@@ -0,0 +1,163 @@
+//go:build linux
+
+package gamebot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/go-vgo/robotgo"
+)
+
+// uinput ioctl requests, see linux/uinput.h.
+const (
+	uiDevCreate  = 0x5501
+	uiDevDestroy = 0x5502
+	uiSetEvBit   = 0x40045564
+	uiSetKeyBit  = 0x40045565
+	uiSetRelBit  = 0x40045566
+	uiSetAbsBit  = 0x40045567
+
+	// uinputNameSize and uinputAbsCount are the size of a device's name and the number of absolute axes in a
+	// struct uinput_user_dev.
+	uinputNameSize = 80
+	uinputAbsCount = 64
+	// busVirtual is the bus the devices are reported on.
+	busVirtual = 0x06
+	// uinputSettleDelay is how long the desktop is given to pick up a new device before input is sent to it.
+	uinputSettleDelay = 200 * time.Millisecond
+)
+
+// NewUinputDriver creates a virtual keyboard and mouse, and a gamepad if `opts.Gamepad` is true, with the uinput
+// module and returns a driver that sends input through them. The process needs write access to /dev/uinput, usually
+// by being in the input group or through a udev rule. Close the driver once the bot has been closed to remove the
+// devices.
+func NewUinputDriver(opts UinputOptions) (*UinputDriver, error) {
+	if opts.ScreenWidth <= 0 || opts.ScreenHeight <= 0 {
+		opts.ScreenWidth, opts.ScreenHeight = robotgo.GetScreenSize()
+	}
+
+	specs := []uinputSpec{keyboardSpec(), mouseSpec(opts)}
+	if opts.Gamepad {
+		specs = append(specs, gamepadSpec())
+	}
+
+	var devices []*uinputDevice
+	for _, spec := range specs {
+		dev, err := createUinputDevice(spec)
+		if err != nil {
+			for _, d := range devices {
+				d.Close()
+			}
+			return nil, err
+		}
+		devices = append(devices, dev)
+	}
+	time.Sleep(uinputSettleDelay)
+
+	d := UinputDevices{Keyboard: devices[0], Mouse: devices[1]}
+	if opts.Gamepad {
+		d.Gamepad = devices[2]
+	}
+
+	return NewUinputDriverFromDevices(d, opts), nil
+}
+
+// uinputDevice is a device created through /dev/uinput. Closing it removes the device.
+type uinputDevice struct {
+	*os.File
+}
+
+// createUinputDevice creates the device described by `spec`.
+func createUinputDevice(spec uinputSpec) (*uinputDevice, error) {
+	f, err := os.OpenFile("/dev/uinput", os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open /dev/uinput: %v", err)
+	}
+	dev := &uinputDevice{File: f}
+
+	if err := dev.setup(spec); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to create %s: %v", spec.name, err)
+	}
+
+	return dev, nil
+}
+
+// setup enables the events of `spec`, writes its struct uinput_user_dev and creates the device.
+func (d *uinputDevice) setup(spec uinputSpec) error {
+	var absCodes []uint16
+	for _, a := range spec.abs {
+		absCodes = append(absCodes, a.code)
+	}
+
+	bits := []struct {
+		request uintptr
+		evType  uintptr
+		codes   []uint16
+	}{
+		{uiSetKeyBit, evKey, spec.keys},
+		{uiSetRelBit, evRel, spec.rels},
+		{uiSetAbsBit, evAbs, absCodes},
+	}
+
+	for _, b := range bits {
+		if len(b.codes) == 0 {
+			continue
+		}
+		if err := d.ioctl(uiSetEvBit, b.evType); err != nil {
+			return err
+		}
+		for _, code := range b.codes {
+			if err := d.ioctl(b.request, uintptr(code)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := d.Write(userDev(spec)); err != nil {
+		return err
+	}
+	return d.ioctl(uiDevCreate, 0)
+}
+
+// userDev encodes the struct uinput_user_dev describing `spec`.
+func userDev(spec uinputSpec) []byte {
+	var absmax, absmin, absfuzz, absflat [uinputAbsCount]int32
+	for _, a := range spec.abs {
+		absmin[a.code], absmax[a.code], absflat[a.code] = a.min, a.max, a.flat
+	}
+
+	var name [uinputNameSize]byte
+	copy(name[:uinputNameSize-1], spec.name)
+
+	var buf bytes.Buffer
+	buf.Write(name[:])
+	binary.Write(&buf, binary.LittleEndian, struct {
+		Bustype, Vendor, Product, Version uint16
+		FFEffectsMax                      uint32
+	}{busVirtual, spec.vendor, spec.product, 1, 0})
+	for _, axes := range [][uinputAbsCount]int32{absmax, absmin, absfuzz, absflat} {
+		binary.Write(&buf, binary.LittleEndian, axes)
+	}
+
+	return buf.Bytes()
+}
+
+// ioctl sends `request` with `arg` to the device.
+func (d *uinputDevice) ioctl(request, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, d.Fd(), request, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// (d *uinputDevice) Close removes the device.
+func (d *uinputDevice) Close() error {
+	d.ioctl(uiDevDestroy, 0)
+	return d.File.Close()
+}
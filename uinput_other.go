@@ -0,0 +1,10 @@
+//go:build !linux
+
+package gamebot
+
+import "errors"
+
+// NewUinputDriver returns an error, as uinput devices can only be created on Linux.
+func NewUinputDriver(opts UinputOptions) (*UinputDriver, error) {
+	return nil, errors.New("uinput devices can only be created on linux")
+}
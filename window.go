@@ -4,7 +4,6 @@ import (
 	"fmt"
 
 	"github.com/go-vgo/robotgo"
-	"github.com/lxn/win"
 )
 
 // WindowPidNotFoundError is returned when the window PID cannot be found.
@@ -59,7 +58,7 @@ type size struct {
 
 type window struct {
 	processName string
-	hwnd        win.HWND
+	hwnd        windowHandle
 	title       string
 	pid         int32
 	position    postiion
@@ -116,7 +115,7 @@ func getWindow(procName string) (*window, error) {
 	id := ids[0]
 	title := robotgo.GetTitle(id)
 	x, y, w, h := robotgo.GetBounds(id)
-	hwnd := robotgo.GetHWND()
+	hwnd := activeWindowHandle()
 
 	return &window{
 		processName: procName,
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package gamebot
+
+// windowHandle is the native handle of a window. Windows are only found by their pid on this platform, so the handle
+// is always 0.
+type windowHandle uintptr
+
+// activeWindowHandle returns the handle of the window that has focus.
+func activeWindowHandle() windowHandle {
+	return 0
+}
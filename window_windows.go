@@ -0,0 +1,16 @@
+//go:build windows
+
+package gamebot
+
+import (
+	"github.com/go-vgo/robotgo"
+	"github.com/lxn/win"
+)
+
+// windowHandle is the native handle of a window.
+type windowHandle = win.HWND
+
+// activeWindowHandle returns the handle of the window that has focus.
+func activeWindowHandle() windowHandle {
+	return robotgo.GetHWND()
+}